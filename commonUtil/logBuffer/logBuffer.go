@@ -5,6 +5,7 @@
 package logBuffer
 
 import (
+    "context"
     "fmt"
     "github.com/ZhouJunjun/goLib/log4j"
     "github.com/ZhouJunjun/goLib/util"
@@ -15,9 +16,11 @@ type Buffer struct {
     buffer      []byte
     total, use  int
     logLevel    log4j.Level
+    levelSet    bool // logLevel的零值为TRACE, 未设置时按INFO处理
     printStack  bool
     runtimeSkip *int
     flagMap     map[string]bool // 自定义标志
+    ctx         context.Context // 日志上下文, log4j.Log时输出其中的traceId等
 }
 
 func (p *Buffer) String() string {
@@ -57,16 +60,32 @@ func (p *Buffer) Is(key string) bool {
     return p != nil && p.flagMap != nil && p.flagMap[key]
 }
 
+// ctx中的日志上下文由log4j.ContextWith存入, 格式化时用%X{key}输出
+func (p *Buffer) SetContext(ctx context.Context) *Buffer {
+    if p == nil {
+        return nil
+    }
+    p.ctx = ctx
+    return p
+}
+
+func (p *Buffer) Context() context.Context {
+    if p == nil {
+        return nil
+    }
+    return p.ctx
+}
+
 func (p *Buffer) SetLogLevel(level log4j.Level) *Buffer {
     if p == nil {
         return nil
     }
-    p.logLevel = level
+    p.logLevel, p.levelSet = level, true
     return p
 }
 
 func (p *Buffer) GetLogLevel() log4j.Level {
-    if p == nil || p.logLevel == 0 {
+    if p == nil || !p.levelSet {
         return log4j.INFO
     }
     return p.logLevel
@@ -82,11 +101,12 @@ func (p *Buffer) SetError(isError bool) *Buffer {
     } else {
         p.logLevel = log4j.INFO
     }
+    p.levelSet = true
     return p
 }
 
 func (p *Buffer) IsError() bool {
-    return p != nil && p.logLevel == log4j.ERROR
+    return p != nil && p.levelSet && p.logLevel >= log4j.ERROR
 }
 
 func (p *Buffer) SetPrintStack(printStack bool) *Buffer {
@@ -0,0 +1,53 @@
+package logBuffer
+
+import (
+    "github.com/ZhouJunjun/goLib/log4j"
+    "strings"
+    "testing"
+)
+
+// logLevel的零值为TRACE, 未设置时应为INFO; 显式设置TRACE时应为TRACE
+func TestGetLogLevel(t *testing.T) {
+    var nilBuffer *Buffer
+    cases := []struct {
+        desc    string
+        buffer  *Buffer
+        level   log4j.Level
+        isError bool
+    }{
+        {"nil", nilBuffer, log4j.INFO, false},
+        {"not set", NewBuffer(), log4j.INFO, false},
+        {"trace", NewBuffer().SetLogLevel(log4j.TRACE), log4j.TRACE, false},
+        {"warning", NewBuffer().SetLogLevel(log4j.WARNING), log4j.WARNING, false},
+        {"fatal", NewBuffer().SetLogLevel(log4j.FATAL), log4j.FATAL, true},
+        {"SetError(true)", NewBuffer().SetError(true), log4j.ERROR, true},
+        {"SetError(false)", NewBuffer().SetError(false), log4j.INFO, false},
+    }
+    for _, c := range cases {
+        if got := c.buffer.GetLogLevel(); got != c.level {
+            t.Errorf("%s: GetLogLevel() = %s, want %s", c.desc, got, c.level)
+        }
+        if got := c.buffer.IsError(); got != c.isError {
+            t.Errorf("%s: IsError() = %v", c.desc, got)
+        }
+    }
+}
+
+func TestLogLevel(t *testing.T) {
+    memory := log4j.NewMemoryLogWriter(log4j.TRACE, 10)
+    logger := log4j.NewLogger()
+    logger.SetLogWriter("memory", memory)
+
+    logger.Log(NewBufferString("trace").SetLogLevel(log4j.TRACE))
+    logger.Log(NewBufferString("default"))
+    logger.LogIfError(NewBufferString("warning").SetLogLevel(log4j.WARNING))
+    logger.LogIfError(NewBufferString("fatal").SetLogLevel(log4j.FATAL)) // 只输出, 不退出进程
+
+    got := make([]string, 0)
+    for _, rec := range memory.Query(log4j.MemoryQuery{}) {
+        got = append(got, rec.Level.String()+" "+rec.Message)
+    }
+    if strings.Join(got, ",") != "TRAC trace,INFO default,FATL fatal" {
+        t.Errorf("got %v", got)
+    }
+}
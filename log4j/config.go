@@ -0,0 +1,980 @@
+package log4j
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 一个<filter>校验通过后的配置
+type filterConfig struct {
+	line  int
+	tag   string
+	typ   string
+	level Level
+	prop  *LogProperty
+}
+
+// 校验通过后的配置
+type logConfig struct {
+	filters    []*filterConfig
+	levelRules *levelRules
+}
+
+// 配置中的一处错误
+type ConfigError struct {
+	Line int    // 所在行, 0表示无法定位
+	Tag  string // 所在<filter>的tag, 可能为空
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	msg := e.Msg
+	if e.Tag != "" {
+		msg = fmt.Sprintf("filter[%s]: %s", e.Tag, msg)
+	}
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", e.Line, msg)
+	}
+	return msg
+}
+
+// 配置中的全部错误
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgSlice := make([]string, len(e))
+	for i, err := range e {
+		msgSlice[i] = err.Error()
+	}
+	return strings.Join(msgSlice, "; ")
+}
+
+// 收集校验配置时发现的错误, 并将xml中的offset换算为行号
+type configChecker struct {
+	contents []byte
+	errs     ConfigErrors
+}
+
+func (c *configChecker) line(offset int64) int {
+	if offset <= 0 || offset > int64(len(c.contents)) {
+		return 0
+	}
+	return bytes.Count(c.contents[:offset], newLine) + 1
+}
+
+func (c *configChecker) addError(offset int64, tag string, format string, args ...interface{}) {
+	c.errs = append(c.errs, &ConfigError{Line: c.line(offset), Tag: tag, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Load XML or JSON configuration; 配置有误时输出全部错误并退出进程
+func (p *Logger) LoadConfiguration(filename string) {
+	if err := p.LoadConfigurationE(filename); err != nil {
+		if errs, ok := err.(ConfigErrors); ok {
+			for _, e := range errs {
+				printlnIO(os.Stderr, "ERROR", e.Error())
+			}
+		} else {
+			printlnIO(os.Stderr, "ERROR", err.Error())
+		}
+		os.Exit(1)
+	}
+}
+
+// 配置有误时返回ConfigErrors(列出全部错误), 且不改变当前的LogWriter
+func (p *Logger) LoadConfigurationE(filename string) error {
+	contents, err := readConfigFile(filename)
+	if err != nil {
+		return err
+	}
+	return p.loadConfiguration(filename, contents)
+}
+
+// 从io.Reader读取xml或json配置; 通过此方式加载的配置不支持Reload
+func (p *Logger) LoadConfigurationFromReader(reader io.Reader) error {
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return ConfigErrors{{Msg: "read configuration err: " + err.Error()}}
+	}
+	return p.loadConfiguration("", contents)
+}
+
+// 只校验配置文件, 不创建LogWriter; 有误时返回ConfigErrors
+func ValidateConfiguration(filename string) error {
+	contents, err := readConfigFile(filename)
+	if err != nil {
+		return err
+	}
+	_, err = parseConfig(contents)
+	return err
+}
+
+// 重新加载最近一次LoadConfiguration的配置文件; 新配置全部校验并创建成功后才替换, 否则保留原配置并返回error
+func (p *Logger) Reload() error {
+	p.lock.RLock()
+	filename := p.configFile
+	p.lock.RUnlock()
+
+	if filename == "" {
+		return errors.New("no configuration file loaded")
+	}
+	return p.LoadConfigurationE(filename)
+}
+
+// 每隔interval检查配置文件的修改时间, 有变化时Reload; 再次调用会替换之前的检查.
+// interval<=0或未通过LoadConfiguration加载配置文件时返回error
+func (p *Logger) WatchConfiguration(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval: %s", interval)
+	}
+	stop := make(chan struct{})
+
+	p.lock.Lock()
+	filename := p.configFile
+	if filename == "" {
+		p.lock.Unlock()
+		return errors.New("no configuration file loaded")
+	}
+	if p.watchStop != nil {
+		close(p.watchStop)
+	}
+	p.watchStop = stop
+	p.lock.Unlock()
+
+	// 返回前取修改时间, 之后的修改都会被发现
+	modTime := time.Time{}
+	if info, err := os.Stat(filename); err == nil {
+		modTime = info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(filename)
+			if err != nil || info.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = info.ModTime()
+
+			if err := p.Reload(); err != nil {
+				printlnIO(os.Stderr, "ERROR", "reload configuration:%s fail, keep old configuration, err:%s", filename, err.Error())
+			} else {
+				printlnIO(os.Stdout, "INFO", "reload configuration:%s success", filename)
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *Logger) StopWatch() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.watchStop != nil {
+		close(p.watchStop)
+		p.watchStop = nil
+	}
+}
+
+func (p *Logger) loadConfiguration(filename string, contents []byte) error {
+	config, err := parseConfig(contents)
+	if err != nil {
+		return err
+	}
+	filters := config.filters
+
+	logWriterMap, err := buildLogWriterMap(filters)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	oldLogWriterMap := p.logWriterMap
+	handOverFiles(oldLogWriterMap, logWriterMap)
+	p.logWriterMap = logWriterMap
+	p.levelRules = config.levelRules
+	p.levelOverrides = keepLevelOverrides(p.levelOverrides, logWriterMap)
+	p.routes = buildRouteRules(filters)
+	p.configFile = filename
+	for _, filter := range filters {
+		if p.defaultLogFilePath == "" && filter.typ == "file" {
+			pathIndex := strings.LastIndex(filter.prop.Filename, "/")
+			p.defaultLogFilePath = filter.prop.Filename[0:pathIndex]
+		}
+	}
+	p.lock.Unlock()
+
+	// 已无日志写入旧的LogWriter, 关闭时会写完缓冲区中的日志
+	for _, logWriter := range oldLogWriterMap {
+		logWriter.Close()
+	}
+	return nil
+}
+
+// 保留新配置中仍有对应LogWriter的未过期运行时级别, 其余的丢弃并提示
+func keepLevelOverrides(overrides map[string]*levelOverride, logWriterMap map[string]LogWriter) map[string]*levelOverride {
+	now := time.Now()
+	for tag, override := range overrides {
+		if override.expired(now) {
+			delete(overrides, tag)
+		} else if _, ok := logWriterMap[tag]; !ok {
+			delete(overrides, tag)
+			printlnIO(os.Stderr, "ERROR", "runtime level %s of %s dropped, log writer not found in new configuration", levelNames[override.level], tag)
+		}
+	}
+	return overrides
+}
+
+// 新旧配置写同一文件的FileLogWriter交接, 避免旧的写完缓冲区时与新的各自切割文件
+func handOverFiles(oldLogWriterMap, logWriterMap map[string]LogWriter) {
+	olds := map[string]*FileLogWriter{}
+	for _, logWriter := range oldLogWriterMap {
+		if flw, ok := unwrapLogWriter(logWriter).(*FileLogWriter); ok {
+			olds[flw.filename] = flw
+		}
+	}
+	for _, logWriter := range logWriterMap {
+		if flw, ok := unwrapLogWriter(logWriter).(*FileLogWriter); ok {
+			if old, ok := olds[flw.filename]; ok {
+				flw.takeOver(old)
+			}
+		}
+	}
+}
+
+func readConfigFile(filename string) ([]byte, error) {
+	// Open the configuration file
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, ConfigErrors{{Msg: fmt.Sprintf("open file:%s err: %s", filename, err.Error())}}
+	}
+	defer fd.Close()
+
+	contents, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return nil, ConfigErrors{{Msg: fmt.Sprintf("read file all:%s err: %s", filename, err.Error())}}
+	}
+	return contents, nil
+}
+
+// 解析xml或json配置(以'{'开头为json), 叠加环境变量后校验所有<filter>、<logger>, 不创建LogWriter; 返回的error为ConfigErrors
+func parseConfig(contents []byte) (*logConfig, error) {
+	c := &configChecker{contents: contents}
+
+	xc := (*xmlLoggerConfig)(nil)
+	if trimmed := bytes.TrimLeft(contents, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		xc = c.decodeJsonConfig()
+	} else {
+		xc = c.decodeXmlConfig()
+	}
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+
+	applyEnvOverlay(xc.Filter)
+
+	config := &logConfig{
+		filters:    c.checkFilters(xc.Filter),
+		levelRules: c.checkLoggers(xc.Logger),
+	}
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+	return config, nil
+}
+
+func (c *configChecker) checkLoggers(xmlLoggers []xmlLogger) *levelRules {
+	rules := make([]levelRule, 0, len(xmlLoggers))
+	nameMap := map[string]bool{}
+
+	for _, xmlLogger := range xmlLoggers {
+		name := strings.TrimSpace(xmlLogger.Name)
+		if name == "" {
+			c.addError(xmlLogger.offset, "", "log logger property:name not found")
+			continue
+		} else if nameMap[name] {
+			c.addError(xmlLogger.offset, "", "log logger property:name's value repeat: %s", name)
+			continue
+		}
+		nameMap[name] = true
+
+		lvl, ok := parseLevel(xmlLogger.Level)
+		if !ok {
+			c.addError(xmlLogger.offset, "", "unsupported logger property:level's value: %s", xmlLogger.Level)
+			continue
+		}
+		rules = append(rules, levelRule{name: name, level: lvl})
+	}
+	return newLevelRules(rules)
+}
+
+func (c *configChecker) checkFilters(xmlFilters []xmlFilter) []*filterConfig {
+
+	filters := make([]*filterConfig, 0, len(xmlFilters))
+	tagMap := map[string]bool{}
+
+	for _, xmlFilter := range xmlFilters {
+		errCount, offset, tag := len(c.errs), xmlFilter.offset, xmlFilter.Tag
+
+		// Check required children
+		if xmlFilter.Enabled == "" {
+			c.addError(offset, tag, "log filter property:enabled not found")
+
+		} else if xmlFilter.Enabled == "false" {
+			continue
+		}
+
+		if tag == "" {
+			c.addError(offset, tag, "log filter child:<tag> not found")
+
+		} else if tagMap[tag] {
+			c.addError(offset, tag, "log filter child:<tag>'s value repeat")
+		}
+		tagMap[tag] = true
+
+		lvl, ok := Level(0), false
+		if xmlFilter.Level == "" {
+			c.addError(offset, tag, "log filter child:<level> not found")
+
+		} else if lvl, ok = parseLevel(xmlFilter.Level); !ok {
+			c.addError(offset, tag, "unsupported filter child:<level>'s value: %s", xmlFilter.Level)
+		}
+
+		prop := (*LogProperty)(nil)
+		switch xmlFilter.Type {
+		case "":
+			c.addError(offset, tag, "log filter child:<type> not found")
+		case "console":
+			prop = c.xmlToConsoleProperty(tag, xmlFilter.Property)
+		case "file":
+			prop = c.xmlToFileProperty(offset, tag, xmlFilter.Property)
+		case "syslog":
+			prop = c.xmlToSyslogProperty(offset, tag, xmlFilter.Property)
+		case "socket":
+			prop = c.xmlToSocketProperty(offset, tag, xmlFilter.Property)
+		case "http":
+			prop = c.xmlToHttpProperty(offset, tag, xmlFilter.Property)
+		case "memory":
+			prop = c.xmlToMemoryProperty(tag, xmlFilter.Property)
+		default:
+			c.addError(offset, tag, "unsupported filter child:<type>'s value: %s", xmlFilter.Type)
+		}
+
+		if prop != nil && prop.HasMaxLevel && ok && prop.MaxLevel < lvl {
+			c.addError(offset, tag, "property maxLevel:%s lower than level:%s", levelNames[prop.MaxLevel], levelNames[lvl])
+		}
+		if prop != nil && !prop.Private && (len(prop.Tags) > 0 || prop.RouteUntagged || prop.Mirror) {
+			c.addError(offset, tag, "property tags, untaggedLevel and mirror require private")
+		}
+
+		if len(c.errs) == errCount {
+			filters = append(filters, &filterConfig{line: c.line(offset), tag: tag, typ: xmlFilter.Type, level: lvl, prop: prop})
+		}
+	}
+	return filters
+}
+
+// 任一LogWriter创建失败时, 关闭已创建的
+func buildLogWriterMap(filters []*filterConfig) (map[string]LogWriter, error) {
+	logWriterMap := make(map[string]LogWriter, len(filters))
+
+	for _, filter := range filters {
+		logWriter, err := newLogWriter(filter)
+		if err != nil {
+			for _, created := range logWriterMap {
+				created.Close()
+			}
+			return nil, ConfigErrors{{Line: filter.line, Tag: filter.tag, Msg: err.Error()}}
+		}
+		logWriterMap[filter.tag] = wrapLogWriter(logWriter, filter.prop)
+	}
+	return logWriterMap, nil
+}
+
+func newLogWriter(filter *filterConfig) (LogWriter, error) {
+	switch filter.typ {
+	case "console":
+		console := NewConsoleLogWriter(filter.level)
+		console.SetFormat(filter.prop.Format)
+		console.SetOverflow(filter.prop.overflowPolicy())
+		if filter.prop.Color != "" {
+			console.SetColor(filter.prop.Color)
+		}
+		console.SetColorLine(filter.prop.ColorLine)
+		if filter.prop.HasStderrLevel {
+			console.SetStderrLevel(filter.prop.StderrLevel)
+		}
+		return console, nil
+	case "file":
+		return newFileLogWriterByProperty(filter.tag, filter.level, filter.prop)
+	case "syslog":
+		return newSyslogLogWriterByProperty(filter.tag, filter.level, filter.prop), nil
+	case "socket":
+		return newSocketLogWriterByProperty(filter.tag, filter.level, filter.prop), nil
+	case "http":
+		return newHttpLogWriterByProperty(filter.tag, filter.level, filter.prop), nil
+	case "memory":
+		return NewMemoryLogWriter(filter.level, filter.prop.MemorySize).SetPrivate(filter.prop.Private), nil
+	default:
+		return nil, fmt.Errorf("unsupported filter child:<type>'s value: %s", filter.typ)
+	}
+}
+
+func parseLevel(str string) (Level, bool) {
+	for lvl, name := range levelNames {
+		if name == str {
+			return Level(lvl), true
+		}
+	}
+	return 0, false
+}
+
+func (c *configChecker) xmlToConsoleProperty(tag string, props []xmlProperty) *LogProperty {
+
+	prop := &LogProperty{Format: defaultFormat, Color: ColorAuto}
+
+	for _, xmlProp := range props {
+		value := strings.Trim(xmlProp.Value, " \r\n")
+		switch xmlProp.Name {
+		case "format":
+			prop.Format = value
+		case "color":
+			switch value {
+			case ColorAuto, ColorAlways, ColorNever:
+				prop.Color = value
+			default:
+				c.addError(xmlProp.offset, tag, "unsupported color: %s", value)
+			}
+		case "colorLine":
+			prop.ColorLine = value != "false"
+		case "stderrLevel":
+			if lvl, ok := parseLevel(value); ok {
+				prop.HasStderrLevel, prop.StderrLevel = true, lvl
+			} else {
+				c.addError(xmlProp.offset, tag, "unsupported stderrLevel: %s", value)
+			}
+		default:
+			if !c.commonProperty(prop, tag, xmlProp) {
+				c.addError(xmlProp.offset, tag, "unsupported filter property: %s", xmlProp.Name)
+			}
+		}
+	}
+	return prop
+}
+
+func (c *configChecker) xmlToFileProperty(offset int64, tag string, props []xmlProperty) *LogProperty {
+
+	prop := &LogProperty{Format: defaultFormat}
+
+	// Parse properties
+	for _, xmlProp := range props {
+		value := strings.Trim(xmlProp.Value, " \r\n")
+		switch xmlProp.Name {
+		case "filename":
+			prop.Filename = value
+		case "format":
+			prop.Format = value
+		case "maxlines":
+			prop.MaxLines = c.numProperty(tag, xmlProp, value, 1000)
+		case "maxsize":
+			prop.Maxsize = c.numProperty(tag, xmlProp, value, 1024)
+		case "daily":
+			prop.Daily = value != "false"
+		case "rotatePeriod":
+			switch value {
+			case RotateMinute, RotateHour, RotateDay, RotateWeek:
+				prop.RotatePeriod = value
+			default:
+				c.addError(xmlProp.offset, tag, "unsupported rotatePeriod: %s", value)
+			}
+		case "rotatePattern":
+			if isValidRotatePattern(value) {
+				prop.RotatePattern = value
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid rotatePattern: %s", value)
+			}
+		case "rotate":
+			prop.Rotate = value != "false"
+		case "private":
+			prop.Private = value != "false"
+		case "keepDay":
+			prop.KeepDay = int64(c.numProperty(tag, xmlProp, value, 1000))
+		case "maxBackups":
+			prop.MaxBackups = c.numProperty(tag, xmlProp, value, 1000)
+		case "maxTotalSize":
+			prop.MaxTotalSize = int64(c.numProperty(tag, xmlProp, value, 1024))
+		case "compress":
+			switch value {
+			case CompressGzip, "true":
+				prop.Compress = CompressGzip
+			case "", "false", "none":
+				prop.Compress = ""
+			default:
+				c.addError(xmlProp.offset, tag, "unsupported compress: %s", value)
+			}
+		default:
+			if !c.commonProperty(prop, tag, xmlProp) {
+				c.addError(xmlProp.offset, tag, "unsupported property: %s", xmlProp.Name)
+			}
+		}
+	}
+
+	// Check properties
+	if len(prop.Filename) == 0 {
+		c.addError(offset, tag, "missing property: filename")
+	}
+	return prop
+}
+
+func (c *configChecker) xmlToSyslogProperty(offset int64, tag string, props []xmlProperty) *LogProperty {
+
+	prop := &LogProperty{Format: "%M", Facility: syslogFacilities["user"], SyslogRFC: SyslogRFC5424}
+
+	for _, xmlProp := range props {
+		value := strings.Trim(xmlProp.Value, " \r\n")
+		switch xmlProp.Name {
+		case "network":
+			switch value {
+			case "", "udp", "tcp", "unix", "unixgram":
+				prop.Network = value
+			default:
+				c.addError(xmlProp.offset, tag, "unsupported network: %s", value)
+			}
+		case "address":
+			prop.Address = value
+		case "facility":
+			if facility, ok := parseSyslogFacility(value); ok {
+				prop.Facility = facility
+			} else {
+				c.addError(xmlProp.offset, tag, "unsupported facility: %s", value)
+			}
+		case "appName":
+			prop.AppName = value
+		case "rfc":
+			if value == SyslogRFC5424 || value == SyslogRFC3164 {
+				prop.SyslogRFC = value
+			} else {
+				c.addError(xmlProp.offset, tag, "unsupported rfc: %s", value)
+			}
+		case "severity":
+			if severity, ok := parseSyslogSeverity(value); ok {
+				prop.SeverityMap = severity
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid severity: %s", value)
+			}
+		case "format":
+			prop.Format = value
+		case "private":
+			prop.Private = value != "false"
+		default:
+			if !c.commonProperty(prop, tag, xmlProp) {
+				c.addError(xmlProp.offset, tag, "unsupported property: %s", xmlProp.Name)
+			}
+		}
+	}
+
+	// 未指定network时连接本机syslog, 不需要address
+	if prop.Network != "" && prop.Address == "" {
+		c.addError(offset, tag, "missing property: address")
+	}
+	return prop
+}
+
+func (c *configChecker) xmlToSocketProperty(offset int64, tag string, props []xmlProperty) *LogProperty {
+
+	prop := &LogProperty{Format: defaultFormat, Network: "tcp", Spill: SpillMemory}
+
+	for _, xmlProp := range props {
+		value := strings.Trim(xmlProp.Value, " \r\n")
+		switch xmlProp.Name {
+		case "network":
+			switch value {
+			case "tcp", "udp":
+				prop.Network = value
+			default:
+				c.addError(xmlProp.offset, tag, "unsupported network: %s", value)
+			}
+		case "address":
+			prop.Address = value
+		case "format":
+			prop.Format = value
+		case "private":
+			prop.Private = value != "false"
+		case "spill":
+			switch value {
+			case SpillMemory, SpillFile:
+				prop.Spill = value
+			default:
+				c.addError(xmlProp.offset, tag, "unsupported spill: %s", value)
+			}
+		case "bufferSize":
+			prop.BufferSize = c.numProperty(tag, xmlProp, value, 1000)
+		case "spillFile":
+			prop.SpillFile = value
+		case "spillMaxSize":
+			prop.SpillMaxSize = int64(c.numProperty(tag, xmlProp, value, 1024))
+		case "maxBackoff":
+			if backoff, err := time.ParseDuration(value); err == nil && backoff > 0 {
+				prop.MaxBackoff = backoff
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid maxBackoff: %s", value)
+			}
+		default:
+			if !c.commonProperty(prop, tag, xmlProp) {
+				c.addError(xmlProp.offset, tag, "unsupported property: %s", xmlProp.Name)
+			}
+		}
+	}
+
+	if prop.Address == "" {
+		c.addError(offset, tag, "missing property: address")
+	}
+	if prop.Spill == SpillFile && prop.SpillFile == "" {
+		c.addError(offset, tag, "missing property: spillFile")
+	}
+	return prop
+}
+
+func (c *configChecker) xmlToHttpProperty(offset int64, tag string, props []xmlProperty) *LogProperty {
+
+	prop := &LogProperty{Header: http.Header{}, MaxRetries: -1}
+
+	for _, xmlProp := range props {
+		value := strings.Trim(xmlProp.Value, " \r\n")
+		switch xmlProp.Name {
+		case "url":
+			if u, err := url.Parse(value); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				prop.URL = value
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid url: %s", value)
+			}
+		case "header":
+			// 格式为"Name: value", 可配置多个
+			if pair := strings.SplitN(value, ":", 2); len(pair) == 2 && strings.TrimSpace(pair[0]) != "" {
+				prop.Header.Add(strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1]))
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid header: %s", value)
+			}
+		case "gzip":
+			prop.Gzip = value != "false"
+		case "private":
+			prop.Private = value != "false"
+		case "batchSize":
+			prop.BatchSize = c.numProperty(tag, xmlProp, value, 1000)
+		case "queueSize":
+			prop.QueueSize = c.numProperty(tag, xmlProp, value, 1000)
+		case "maxRetries":
+			if retries, err := strconv.Atoi(value); err == nil && retries >= 0 {
+				prop.MaxRetries = retries
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid maxRetries: %s", value)
+			}
+		case "batchLatency", "maxBackoff", "timeout":
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				c.addError(xmlProp.offset, tag, "invalid %s: %s", xmlProp.Name, value)
+			} else if xmlProp.Name == "batchLatency" {
+				prop.BatchLatency = duration
+			} else if xmlProp.Name == "maxBackoff" {
+				prop.MaxBackoff = duration
+			} else {
+				prop.Timeout = duration
+			}
+		default:
+			if !c.commonProperty(prop, tag, xmlProp) {
+				c.addError(xmlProp.offset, tag, "unsupported property: %s", xmlProp.Name)
+			}
+		}
+	}
+
+	if prop.URL == "" {
+		c.addError(offset, tag, "missing property: url")
+	}
+	// 未配置缓冲区满时的处理策略时与NewHttpLogWriter一致, 丢弃最旧的日志
+	if !hasProperty(props, "overflow") && !hasProperty(props, "overflowTimeout") {
+		prop.Overflow = OverflowDropOldest
+	}
+	return prop
+}
+
+func hasProperty(props []xmlProperty, name string) bool {
+	for _, xmlProp := range props {
+		if xmlProp.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *configChecker) xmlToMemoryProperty(tag string, props []xmlProperty) *LogProperty {
+
+	prop := &LogProperty{}
+
+	for _, xmlProp := range props {
+		value := strings.Trim(xmlProp.Value, " \r\n")
+		switch xmlProp.Name {
+		case "size":
+			if size, err := strToNumSuffix(value, 1000); err == nil && size > 0 {
+				prop.MemorySize = size
+			} else {
+				c.addError(xmlProp.offset, tag, "invalid size: %s", value)
+			}
+		case "private":
+			prop.Private = value != "false"
+		default:
+			if !c.wrapperProperty(prop, tag, xmlProp) {
+				c.addError(xmlProp.offset, tag, "unsupported property: %s", xmlProp.Name)
+			}
+		}
+	}
+	return prop
+}
+
+// 各类型LogWriter都支持的property, 返回false表示不认识该property
+func (c *configChecker) commonProperty(prop *LogProperty, tag string, xmlProp xmlProperty) bool {
+	value := strings.Trim(xmlProp.Value, " \r\n")
+	switch xmlProp.Name {
+	case "overflow":
+		if policy, ok := parseOverflowPolicy(value); ok {
+			prop.Overflow = policy
+		} else {
+			c.addError(xmlProp.offset, tag, "unsupported overflow: %s", value)
+		}
+	case "overflowTimeout":
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			prop.OverflowTimeout = timeout
+		} else {
+			c.addError(xmlProp.offset, tag, "invalid overflowTimeout: %s", value)
+		}
+	default:
+		return c.wrapperProperty(prop, tag, xmlProp)
+	}
+	return true
+}
+
+// 以包装LogWriter实现的property, 所有类型都支持
+func (c *configChecker) wrapperProperty(prop *LogProperty, tag string, xmlProp xmlProperty) bool {
+	value := strings.Trim(xmlProp.Value, " \r\n")
+	switch xmlProp.Name {
+	case "suppressWindow":
+		if window, err := time.ParseDuration(value); err == nil && window >= 0 {
+			prop.SuppressWindow = window
+		} else {
+			c.addError(xmlProp.offset, tag, "invalid suppressWindow: %s", value)
+		}
+	case "include", "exclude":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			c.addError(xmlProp.offset, tag, "invalid %s: %s", xmlProp.Name, err.Error())
+		} else if xmlProp.Name == "include" {
+			prop.Include = re
+		} else {
+			prop.Exclude = re
+		}
+	case "includeSource", "excludeSource":
+		// 逗号分隔的包/函数路径前缀
+		prefixes := make([]string, 0)
+		for _, prefix := range strings.Split(value, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+		if xmlProp.Name == "includeSource" {
+			prop.IncludeSource = prefixes
+		} else {
+			prop.ExcludeSource = prefixes
+		}
+	case "maxLevel":
+		if lvl, ok := parseLevel(value); ok {
+			prop.HasMaxLevel, prop.MaxLevel = true, lvl
+		} else {
+			c.addError(xmlProp.offset, tag, "unsupported maxLevel: %s", value)
+		}
+	case "sampleFirst", "sampleThereafter":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.addError(xmlProp.offset, tag, "invalid %s: %s", xmlProp.Name, value)
+		} else if xmlProp.Name == "sampleFirst" {
+			prop.SampleFirst = n
+		} else {
+			prop.SampleThereafter = n
+		}
+	default:
+		return c.routeProperty(prop, tag, xmlProp)
+	}
+	return true
+}
+
+// 私有LogWriter的路由property
+func (c *configChecker) routeProperty(prop *LogProperty, tag string, xmlProp xmlProperty) bool {
+	value := strings.Trim(xmlProp.Value, " \r\n")
+	switch xmlProp.Name {
+	case "tags":
+		// 逗号分隔的glob, 如"order-*,pay"
+		for _, pattern := range strings.Split(value, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				c.addError(xmlProp.offset, tag, "invalid tags pattern: %s", pattern)
+				continue
+			}
+			prop.Tags = append(prop.Tags, pattern)
+		}
+	case "untaggedLevel":
+		if lvl, ok := parseLevel(value); ok {
+			prop.RouteUntagged, prop.UntaggedLevel = true, lvl
+		} else {
+			c.addError(xmlProp.offset, tag, "unsupported untaggedLevel: %s", value)
+		}
+	case "mirror":
+		prop.Mirror = value != "false"
+	default:
+		return false
+	}
+	return true
+}
+
+// 按property包装LogWriter, 未配置时返回原LogWriter; 先过滤, 再抑制重复日志
+func wrapLogWriter(logWriter LogWriter, prop *LogProperty) LogWriter {
+	if prop == nil {
+		return logWriter
+	}
+	if prop.SuppressWindow > 0 || prop.SampleFirst > 0 || prop.SampleThereafter > 0 {
+		logWriter = NewSuppressLogWriter(logWriter, prop.SuppressWindow, prop.SampleFirst, prop.SampleThereafter)
+	}
+	if prop.Include != nil || prop.Exclude != nil || len(prop.IncludeSource) > 0 || len(prop.ExcludeSource) > 0 || prop.HasMaxLevel {
+		filter := NewFilterLogWriter(logWriter).SetInclude(prop.Include).SetExclude(prop.Exclude)
+		filter.SetIncludeSource(prop.IncludeSource).SetExcludeSource(prop.ExcludeSource)
+		if prop.HasMaxLevel {
+			filter.SetMaxLevel(prop.MaxLevel)
+		}
+		logWriter = filter
+	}
+	return logWriter
+}
+
+func newFileLogWriterByProperty(tag string, lvl Level, prop *LogProperty) (*FileLogWriter, error) {
+	flw, err := NewFileLogWriter(tag, lvl, prop.Filename, prop.Rotate, prop.KeepDay)
+	if err != nil {
+		return nil, err
+	}
+	flw.SetFormat(prop.Format)
+	flw.SetRotateLines(prop.MaxLines)
+	flw.SetRotateSize(prop.Maxsize)
+	flw.SetRotateDaily(prop.Daily)
+	flw.SetRotatePeriod(prop.RotatePeriod)
+	flw.SetRotatePattern(prop.RotatePattern)
+	flw.SetPrivate(prop.Private)
+	flw.SetOverflow(prop.overflowPolicy())
+	flw.SetCompress(prop.Compress)
+	flw.SetMaxBackups(prop.MaxBackups)
+	flw.SetMaxTotalSize(prop.MaxTotalSize)
+	return flw, nil
+}
+
+func newSyslogLogWriterByProperty(tag string, lvl Level, prop *LogProperty) *SyslogLogWriter {
+	slw := NewSyslogLogWriter(tag, lvl, prop.Network, prop.Address)
+	slw.SetFormat(prop.Format)
+	slw.SetFacility(prop.Facility)
+	slw.SetAppName(prop.AppName)
+	slw.SetRFC(prop.SyslogRFC)
+	slw.SetSeverity(prop.SeverityMap)
+	slw.SetPrivate(prop.Private)
+	slw.SetOverflow(prop.overflowPolicy())
+	return slw
+}
+
+func newSocketLogWriterByProperty(tag string, lvl Level, prop *LogProperty) *SocketLogWriter {
+	slw := NewSocketLogWriter(tag, lvl, prop.Network, prop.Address)
+	slw.SetFormat(prop.Format)
+	slw.SetPrivate(prop.Private)
+	slw.SetMaxBackoff(prop.MaxBackoff)
+	slw.SetBufferSize(prop.BufferSize)
+	slw.SetOverflow(prop.overflowPolicy())
+	if prop.Spill == SpillFile {
+		slw.SetSpillFile(prop.SpillFile, prop.SpillMaxSize)
+	}
+	return slw
+}
+
+func newHttpLogWriterByProperty(tag string, lvl Level, prop *LogProperty) *HttpLogWriter {
+	hlw := NewHttpLogWriter(tag, lvl, prop.URL, prop.QueueSize)
+	for name, values := range prop.Header {
+		for _, value := range values {
+			hlw.SetHeader(name, value)
+		}
+	}
+	hlw.SetGzip(prop.Gzip)
+	hlw.SetBatch(prop.BatchSize, prop.BatchLatency)
+	hlw.SetRetry(prop.MaxRetries, prop.MaxBackoff)
+	hlw.SetTimeout(prop.Timeout)
+	hlw.SetPrivate(prop.Private)
+	hlw.SetOverflow(prop.overflowPolicy())
+	return hlw
+}
+
+// 时间后缀格式须包含时间元素, 不含路径分隔符, 且能从文件名解析回来(用于按时间排序切割文件)
+func isValidRotatePattern(pattern string) bool {
+	now := time.Now()
+	stamp := now.Format(pattern)
+	if stamp == pattern || strings.ContainsAny(stamp, "/\\") {
+		return false
+	}
+	_, err := time.ParseInLocation(pattern, stamp, time.Local)
+	return err == nil
+}
+
+// Parse a number with K/M/G suffixes based on thousands (1000) or 2^10 (1024)
+// 支持K、M、G后缀, 如10M; 为空时返回0
+func strToNumSuffix(str string, multiple int) (int, error) {
+	if str == "" {
+		return 0, nil
+	}
+	num := 1
+	if len(str) > 1 {
+		switch str[len(str)-1] {
+		case 'G', 'g':
+			num *= multiple
+			fallthrough
+		case 'M', 'm':
+			num *= multiple
+			fallthrough
+		case 'K', 'k':
+			num *= multiple
+			str = str[0 : len(str)-1]
+		}
+	}
+	parsed, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %s", str)
+	}
+	return parsed * num, nil
+}
+
+// 解析数值property, 无法解析时记录错误
+func (c *configChecker) numProperty(tag string, xmlProp xmlProperty, value string, multiple int) int {
+	num, err := strToNumSuffix(value, multiple)
+	if err != nil {
+		c.addError(xmlProp.offset, tag, "invalid %s: %s", xmlProp.Name, value)
+	}
+	return num
+}
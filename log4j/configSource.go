@@ -0,0 +1,236 @@
+package log4j
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 所有LogWriter支持的property名称, 用于环境变量覆盖时还原大小写; 新增property时须同时添加, 由TestPropertyNamesComplete检查
+var propertyNames = []string{
+	"filename", "format", "maxlines", "maxsize", "daily", "rotate", "private", "keepDay",
+	"overflow", "overflowTimeout", "compress", "maxBackups", "maxTotalSize", "rotatePeriod", "rotatePattern",
+	"network", "address", "facility", "appName", "rfc", "severity",
+	"spill", "bufferSize", "spillFile", "spillMaxSize", "maxBackoff",
+	"url", "header", "gzip", "batchSize", "batchLatency", "queueSize", "maxRetries", "timeout",
+	"size", "suppressWindow", "sampleFirst", "sampleThereafter",
+	"tags", "untaggedLevel", "mirror",
+	"include", "exclude", "includeSource", "excludeSource", "maxLevel",
+	"color", "colorLine", "stderrLevel",
+}
+
+func (c *configChecker) decodeXmlConfig() *xmlLoggerConfig {
+	xc := new(xmlLoggerConfig)
+	if err := xml.Unmarshal(c.contents, xc); err != nil {
+		line := 0
+		if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+			line = syntaxErr.Line
+		}
+		c.errs = append(c.errs, &ConfigError{Line: line, Msg: "xml.Unmarshal err: " + err.Error()})
+		return nil
+	}
+	return xc
+}
+
+// 转换为xmlFilter, 复用同一套校验
+func (c *configChecker) decodeJsonConfig() *xmlLoggerConfig {
+	jc := new(jsonLoggerConfig)
+	decoder := json.NewDecoder(bytes.NewReader(c.contents))
+	decoder.UseNumber() // 数值保持原文, 如10485760不会变成1.048576e+07
+	if err := decoder.Decode(jc); err != nil {
+		offset := int64(0)
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			offset = syntaxErr.Offset
+		} else if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			offset = typeErr.Offset
+		}
+		c.addError(offset, "", "json.Unmarshal err: %s", err.Error())
+		return nil
+	}
+
+	offsets := jsonConfigOffsets(c.contents)
+	for i := range jc.Loggers {
+		if i < len(offsets.loggers) {
+			jc.Loggers[i].offset = offsets.loggers[i]
+		}
+	}
+
+	xmlFilters := make([]xmlFilter, len(jc.Filters))
+	for i, filter := range jc.Filters {
+		xmlFilters[i] = xmlFilter{Tag: filter.Tag, Level: filter.Level, Type: filter.Type}
+		propOffsets := map[string]int64(nil)
+		if i < len(offsets.filters) {
+			xmlFilters[i].offset, propOffsets = offsets.filters[i], offsets.properties[i]
+		}
+		if filter.Enabled != nil {
+			xmlFilters[i].Enabled = strconv.FormatBool(*filter.Enabled)
+		}
+
+		// map无序, 按名称排序保证错误提示顺序稳定
+		names := make([]string, 0, len(filter.Properties))
+		for name := range filter.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			// 数组表示可重复的property, 如多个header
+			values, ok := filter.Properties[name].([]interface{})
+			if !ok {
+				values = []interface{}{filter.Properties[name]}
+			}
+			for _, value := range values {
+				xmlProp := xmlProperty{Name: name, offset: propOffsets[name]}
+				if str, ok := value.(string); ok {
+					xmlProp.Value = str
+				} else {
+					xmlProp.Value = fmt.Sprint(value)
+				}
+				xmlFilters[i].Property = append(xmlFilters[i].Property, xmlProp)
+			}
+		}
+	}
+	return &xmlLoggerConfig{Filter: xmlFilters, Logger: jc.Loggers}
+}
+
+// json配置中各filter、property、logger的位置, 用于错误提示行号
+type jsonOffsets struct {
+	filters    []int64
+	properties []map[string]int64 // 与filters一一对应, property名称 -> 位置
+	loggers    []int64
+}
+
+// 已确认json格式正确后调用, 逐个token遍历记录位置
+func jsonConfigOffsets(contents []byte) *jsonOffsets {
+	offsets := &jsonOffsets{}
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+
+	_ = walkJsonObject(decoder, func(key string, offset int64) error {
+		switch key {
+		case "filters":
+			return walkJsonArray(decoder, func(offset int64) error {
+				properties := map[string]int64{}
+				offsets.filters = append(offsets.filters, skipJsonSpace(contents, offset))
+				offsets.properties = append(offsets.properties, properties)
+				return walkJsonObject(decoder, func(key string, offset int64) error {
+					if key != "properties" {
+						return skipJsonValue(decoder)
+					}
+					return walkJsonObject(decoder, func(key string, offset int64) error {
+						properties[key] = offset
+						return skipJsonValue(decoder)
+					})
+				})
+			})
+		case "loggers":
+			return walkJsonArray(decoder, func(offset int64) error {
+				offsets.loggers = append(offsets.loggers, skipJsonSpace(contents, offset))
+				return skipJsonValue(decoder)
+			})
+		default:
+			return skipJsonValue(decoder)
+		}
+	})
+	return offsets
+}
+
+// 对每个key调用fn, fn须读完对应的值; offset为key结束的位置
+func walkJsonObject(decoder *json.Decoder, fn func(key string, offset int64) error) error {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("expect json object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		if err := fn(key, decoder.InputOffset()); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token() // '}'
+	return err
+}
+
+// 对每个元素调用fn, fn须读完该元素; offset为上一个token结束的位置
+func walkJsonArray(decoder *json.Decoder, fn func(offset int64) error) error {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return fmt.Errorf("expect json array")
+	}
+	for decoder.More() {
+		if err := fn(decoder.InputOffset()); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token() // ']'
+	return err
+}
+
+// 跳过offset之后的空白及逗号, 返回下一个值第一个字符之后的位置(不为0, 0表示位置未知)
+func skipJsonSpace(contents []byte, offset int64) int64 {
+	for offset < int64(len(contents)) && strings.IndexByte(" \t\r\n,", contents[offset]) >= 0 {
+		offset++
+	}
+	return offset + 1
+}
+
+func skipJsonValue(decoder *json.Decoder) error {
+	var raw json.RawMessage
+	return decoder.Decode(&raw)
+}
+
+// 环境变量覆盖配置文件中的值: LOG4J_<TAG>_ENABLED、LOG4J_<TAG>_LEVEL、LOG4J_<TAG>_<PROPERTY>,
+// TAG及PROPERTY为大写, TAG中字母数字以外的字符替换为'_', 如tag为access-log时用LOG4J_ACCESS_LOG_FILENAME.
+// 只作用于配置中已有的<filter>; 其中没有的property会被添加, PROPERTY须在propertyNames中
+func applyEnvOverlay(xmlFilters []xmlFilter) {
+	for i := range xmlFilters {
+		filter := &xmlFilters[i]
+		if filter.Tag == "" {
+			continue
+		}
+		prefix := "LOG4J_" + envName(filter.Tag) + "_"
+
+		if value, ok := os.LookupEnv(prefix + "ENABLED"); ok {
+			filter.Enabled = value
+		}
+		if value, ok := os.LookupEnv(prefix + "LEVEL"); ok {
+			filter.Level = value
+		}
+
+		for _, name := range propertyNames {
+			value, ok := os.LookupEnv(prefix + envName(name))
+			if !ok {
+				continue
+			}
+
+			replaced := false
+			for j := range filter.Property {
+				if filter.Property[j].Name == name {
+					filter.Property[j].Value, replaced = value, true
+				}
+			}
+			if !replaced {
+				filter.Property = append(filter.Property, xmlProperty{Name: name, Value: value})
+			}
+		}
+	}
+}
+
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,228 @@
+package log4j
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testXmlConfig = `<logging>
+<filter enabled="true">
+  <tag>app</tag>
+  <type>file</type>
+  <level>INFO</level>
+  <property name="filename">/tmp/log4j/app.log</property>
+  <property name="maxsize">10M</property>
+  <property name="rotate">true</property>
+  <property name="keepDay">7</property>
+  <property name="format">json</property>
+</filter>
+<filter enabled="true">
+  <tag>ship</tag>
+  <type>http</type>
+  <level>WARNING</level>
+  <property name="url">http://127.0.0.1/logs</property>
+  <property name="header">Authorization: Bearer t</property>
+  <property name="header">X-App: order</property>
+  <property name="batchSize">100</property>
+  <property name="private">true</property>
+</filter>
+<filter enabled="false">
+  <tag>off</tag>
+</filter>
+<logger name="root" level="INFO"/>
+<logger name="github.com/acme/order" level="DEBUG"/>
+</logging>`
+
+const testJsonConfig = `{
+  "filters": [
+    {"enabled": true, "tag": "app", "type": "file", "level": "INFO", "properties": {
+      "filename": "/tmp/log4j/app.log", "maxsize": "10M", "rotate": true, "keepDay": 7, "format": "json"}},
+    {"enabled": true, "tag": "ship", "type": "http", "level": "WARNING", "properties": {
+      "url": "http://127.0.0.1/logs", "header": ["Authorization: Bearer t", "X-App: order"], "batchSize": 100, "private": "true"}},
+    {"enabled": false, "tag": "off"}
+  ],
+  "loggers": [
+    {"name": "root", "level": "INFO"},
+    {"name": "github.com/acme/order", "level": "DEBUG"}
+  ]
+}`
+
+// 同样内容的xml与json配置解析结果一致
+func TestJsonXmlEquivalent(t *testing.T) {
+	xmlConfig, err := parseConfig([]byte(testXmlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonConfig, err := parseConfig([]byte(testJsonConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(xmlConfig.filters) != 2 || len(jsonConfig.filters) != 2 {
+		t.Fatalf("got %d xml filters, %d json filters", len(xmlConfig.filters), len(jsonConfig.filters))
+	}
+	for i := range xmlConfig.filters {
+		xmlFilter, jsonFilter := *xmlConfig.filters[i], *jsonConfig.filters[i]
+		xmlFilter.line, jsonFilter.line = 0, 0
+		if !reflect.DeepEqual(xmlFilter.prop, jsonFilter.prop) {
+			t.Errorf("filter %s property:\n xml %+v\njson %+v", xmlFilter.tag, *xmlFilter.prop, *jsonFilter.prop)
+		}
+		xmlFilter.prop, jsonFilter.prop = nil, nil
+		if xmlFilter != jsonFilter {
+			t.Errorf("filter %d:\n xml %+v\njson %+v", i, xmlFilter, jsonFilter)
+		}
+	}
+	if prop := jsonConfig.filters[0].prop; prop.Maxsize != 10*1024*1024 || prop.KeepDay != 7 {
+		t.Errorf("json numbers: maxsize %d, keepDay %d", prop.Maxsize, prop.KeepDay)
+	}
+
+	if !reflect.DeepEqual(xmlConfig.levelRules.rules, jsonConfig.levelRules.rules) ||
+		!reflect.DeepEqual(xmlConfig.levelRules.root, jsonConfig.levelRules.root) {
+		t.Errorf("loggers:\n xml %+v %+v\njson %+v %+v", xmlConfig.levelRules.rules, xmlConfig.levelRules.root,
+			jsonConfig.levelRules.rules, jsonConfig.levelRules.root)
+	}
+}
+
+func TestJsonConfigErrors(t *testing.T) {
+	_, err := parseConfig([]byte(`{
+  "filters": [
+    {"enabled": true, "tag": "app", "type": "file", "level": "INFO",
+     "properties": {
+       "filename": "/tmp/log4j/app.log",
+       "maxsize": 1.5,
+       "compress": "zip"
+     }},
+    {"enabled": true, "tag": "app", "type": "console", "level": "INFO"}
+  ],
+  "loggers": [
+    {"name": "github.com/acme", "level": "INFO"},
+    {"name": "github.com/acme", "level": "INFO"}
+  ]
+}`))
+	want := []ConfigError{
+		{7, "app", "unsupported compress: zip"}, // property按名称排序校验
+		{6, "app", "invalid maxsize: 1.5"},
+		{9, "app", "log filter child:<tag>'s value repeat"},
+		{13, "", "log logger property:name's value repeat: github.com/acme"},
+	}
+	errs, ok := err.(ConfigErrors)
+	if !ok || len(errs) != len(want) {
+		t.Fatalf("expect %d errors, got %v", len(want), err)
+	}
+	for i := range want {
+		if *errs[i] != want[i] {
+			t.Errorf("error %d: got %+v, want %+v", i, *errs[i], want[i])
+		}
+	}
+
+	// 语法及类型错误
+	for contents, line := range map[string]int{
+		"{\"filters\": [\n{\"tag\": 1}\n]}":        2,
+		"{\"filters\": [\n{\"tag\": \"a\",\n}\n]}": 3,
+	} {
+		_, err := parseConfig([]byte(contents))
+		if errs, ok := err.(ConfigErrors); !ok || len(errs) != 1 || errs[0].Line != line {
+			t.Errorf("%q: got %v, want line %d", contents, err, line)
+		}
+	}
+}
+
+func setEnv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, old)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestEnvOverlay(t *testing.T) {
+	setEnv(t, "LOG4J_APP_LEVEL", "DEBUG")
+	setEnv(t, "LOG4J_APP_MAXSIZE", "1K")       // 覆盖已有的property
+	setEnv(t, "LOG4J_APP_MAXBACKUPS", "3")     // 添加配置中没有的property
+	setEnv(t, "LOG4J_SHIP_ENABLED", "false")   // 禁用
+	setEnv(t, "LOG4J_OFF_ENABLED", "true")     // 启用后缺少type等, 报错
+	setEnv(t, "LOG4J_OTHER_LEVEL", "NOT_USED") // 不存在的tag不影响
+
+	_, err := parseConfig([]byte(testXmlConfig))
+	if errs, ok := err.(ConfigErrors); !ok || len(errs) != 2 || errs[0].Tag != "off" {
+		t.Fatalf("expect 2 errors of filter off, got %v", err)
+	}
+
+	setEnv(t, "LOG4J_OFF_ENABLED", "false")
+	config, err := parseConfig([]byte(testXmlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.filters) != 1 {
+		t.Fatalf("expect only filter app, got %d filters", len(config.filters))
+	}
+	app := config.filters[0]
+	if app.level != DEBUG || app.prop.Maxsize != 1024 || app.prop.MaxBackups != 3 || app.prop.KeepDay != 7 {
+		t.Errorf("app: level %s, maxsize %d, maxBackups %d, keepDay %d", app.level, app.prop.Maxsize, app.prop.MaxBackups, app.prop.KeepDay)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for name, want := range map[string]string{"access-log": "ACCESS_LOG", "app.v2": "APP_V2", "maxTotalSize": "MAXTOTALSIZE"} {
+		if got := envName(name); got != want {
+			t.Errorf("envName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+// config.go中按xmlProp.Name解析的property都须在propertyNames中, 否则无法通过环境变量设置
+func TestPropertyNamesComplete(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handled := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switchStmt, ok := node.(*ast.SwitchStmt)
+		if !ok {
+			return true
+		}
+		if selector, ok := switchStmt.Tag.(*ast.SelectorExpr); !ok || selector.Sel.Name != "Name" {
+			return true
+		}
+		for _, stmt := range switchStmt.Body.List {
+			for _, expr := range stmt.(*ast.CaseClause).List {
+				if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					name, _ := strconv.Unquote(lit.Value)
+					handled[name] = true
+				}
+			}
+		}
+		return true
+	})
+	if len(handled) < 10 {
+		t.Fatalf("found only %d properties in config.go", len(handled))
+	}
+
+	names := map[string]bool{}
+	for _, name := range propertyNames {
+		names[name] = true
+	}
+	missing := make([]string, 0)
+	for name := range handled {
+		if !names[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		t.Errorf("properties missing from propertyNames: %s", strings.Join(missing, ", "))
+	}
+}
@@ -0,0 +1,311 @@
+package log4j
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "log4j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func writeFile(t *testing.T, filename, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filename, []byte(contents), 0660); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 只含memory类型<filter>的xml配置, levels为 tag -> 级别
+func memoryConfig(levels ...string) string {
+	config := "<logging>\n"
+	for i := 0; i+1 < len(levels); i += 2 {
+		config += fmt.Sprintf("<filter enabled=\"true\"><tag>%s</tag><type>memory</type><level>%s</level></filter>\n", levels[i], levels[i+1])
+	}
+	return config + "</logging>\n"
+}
+
+func memoryMessages(logger *Logger, tag string) []string {
+	messages := make([]string, 0)
+	if memory := logger.MemoryLogWriters()[tag]; memory != nil {
+		for _, rec := range memory.Query(MemoryQuery{}) {
+			messages = append(messages, rec.Message)
+		}
+	}
+	return messages
+}
+
+func TestReload(t *testing.T) {
+	filename := filepath.Join(tempDir(t), "log4j.xml")
+	writeFile(t, filename, memoryConfig("mem", "DEBUG"))
+
+	logger := NewLogger()
+	defer logger.Close()
+	if err := logger.LoadConfigurationE(filename); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("before")
+
+	writeFile(t, filename, memoryConfig("mem", "WARNING"))
+	if err := logger.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("after")
+	logger.Warn("after warn")
+	if got := memoryMessages(logger, "mem"); len(got) != 1 || got[0] != "after warn" {
+		t.Errorf("after reload: %v", got)
+	}
+
+	// 新配置有误时保留原配置
+	writeFile(t, filename, memoryConfig("mem", "LOUD"))
+	if err := logger.Reload(); err == nil {
+		t.Error("expect reload error")
+	}
+	if levels := logger.GetLevels(); levels["mem"] != WARNING {
+		t.Errorf("levels after failed reload: %v", levels)
+	}
+
+	if err := NewLogger().Reload(); err == nil {
+		t.Error("expect error without configuration file")
+	}
+}
+
+func TestReloadKeepsLevelOverrides(t *testing.T) {
+	filename := filepath.Join(tempDir(t), "log4j.xml")
+	writeFile(t, filename, memoryConfig("a", "INFO", "b", "INFO", "c", "INFO"))
+
+	logger := NewLogger()
+	defer logger.Close()
+	if err := logger.LoadConfigurationE(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetLevel("a", DEBUG); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetLevelFor("b", TRACE, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetLevel("c", ERROR); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// c不在新配置中, b已过期
+	writeFile(t, filename, memoryConfig("a", "WARNING", "b", "WARNING"))
+	if err := logger.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	levels := logger.GetLevels()
+	if len(levels) != 2 || levels["a"] != DEBUG || levels["b"] != WARNING {
+		t.Errorf("levels after reload: %v", levels)
+	}
+
+	logger.Debug("debug")
+	if got := memoryMessages(logger, "a"); len(got) != 1 || got[0] != "debug" {
+		t.Errorf("a: %v", got)
+	}
+}
+
+func TestWatchConfiguration(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+	if err := logger.WatchConfiguration(time.Second); err == nil {
+		t.Error("expect error without configuration file")
+	}
+
+	filename := filepath.Join(tempDir(t), "log4j.xml")
+	writeFile(t, filename, memoryConfig("mem", "INFO"))
+	if err := logger.LoadConfigurationE(filename); err != nil {
+		t.Fatal(err)
+	}
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := logger.WatchConfiguration(interval); err == nil {
+			t.Errorf("expect error for interval %s", interval)
+		}
+	}
+	if err := logger.WatchConfiguration(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filename, memoryConfig("mem", "ERROR"))
+	modTime := time.Now().Add(time.Second) // 避免文件系统的时间精度不足
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); logger.GetLevels()["mem"] != ERROR; {
+		if time.Now().After(deadline) {
+			t.Fatalf("configuration not reloaded, levels: %v", logger.GetLevels())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 停止后不再重新加载
+	logger.StopWatch()
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, filename, memoryConfig("mem", "DEBUG"))
+	modTime = modTime.Add(time.Second)
+	_ = os.Chtimes(filename, modTime, modTime)
+	time.Sleep(50 * time.Millisecond)
+	if levels := logger.GetLevels(); levels["mem"] != ERROR {
+		t.Errorf("reloaded after StopWatch: %v", levels)
+	}
+}
+
+// 写日志的同时多次Reload, 新旧FileLogWriter交接同一文件, 切割后不丢失、不重复
+func TestReloadFileHandOver(t *testing.T) {
+	dir := tempDir(t)
+	filename := filepath.Join(dir, "log4j.xml")
+	writeFile(t, filename, `<logging><filter enabled="true"><tag>file</tag><type>file</type><level>INFO</level>
+<property name="filename">`+filepath.Join(dir, "app.log")+`</property>
+<property name="format">%M</property>
+<property name="rotate">true</property>
+<property name="maxlines">50</property>
+</filter></logging>`)
+
+	logger := NewLogger()
+	if err := logger.LoadConfigurationE(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	total, stop := 0, make(chan bool)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ; ; total++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			logger.Info("line-%d", total)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := logger.Reload(); err != nil {
+			t.Error(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	// 交接完成后按maxlines切割
+	for end := total + 200; total < end; total++ {
+		logger.Info("line-%d", total)
+	}
+	logger.Close()
+
+	seen := map[string]int{}
+	files, _ := filepath.Glob(filepath.Join(dir, "app.log*"))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for scanner := bufio.NewScanner(f); scanner.Scan(); {
+			seen[scanner.Text()]++
+		}
+		_ = f.Close()
+	}
+	if len(files) < 2 {
+		t.Errorf("expect rotated files, got %s", strings.Join(files, ","))
+	}
+	for i := 0; i < total; i++ {
+		if line := fmt.Sprintf("line-%d", i); seen[line] != 1 {
+			t.Errorf("%s written %d times", line, seen[line])
+		}
+	}
+	if len(seen) != total {
+		t.Errorf("got %d distinct lines, want %d", len(seen), total)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	_, err := parseConfig([]byte(`<logging>
+<filter enabled="true">
+  <tag>file</tag>
+  <type>file</type>
+  <level>LOUD</level>
+  <property name="maxsize">10x</property>
+</filter>
+<filter enabled="true">
+  <tag>file</tag>
+  <type>console</type>
+  <level>INFO</level>
+  <property name="colour">always</property>
+</filter>
+<filter>
+  <tag>x</tag>
+</filter>
+<logger name="github.com/acme" level="NOISY"/>
+</logging>`))
+
+	want := []ConfigError{
+		{2, "file", "unsupported filter child:<level>'s value: LOUD"},
+		{6, "file", "invalid maxsize: 10x"},
+		{2, "file", "missing property: filename"},
+		{8, "file", "log filter child:<tag>'s value repeat"},
+		{12, "file", "unsupported filter property: colour"},
+		{14, "x", "log filter property:enabled not found"},
+		{14, "x", "log filter child:<level> not found"},
+		{14, "x", "log filter child:<type> not found"},
+		{17, "", "unsupported logger property:level's value: NOISY"},
+	}
+	errs, ok := err.(ConfigErrors)
+	if !ok || len(errs) != len(want) {
+		t.Fatalf("expect %d errors, got %v", len(want), err)
+	}
+	for i := range want {
+		if *errs[i] != want[i] {
+			t.Errorf("error %d: got %+v, want %+v", i, *errs[i], want[i])
+		}
+	}
+	if got := errs[1].Error(); got != "line 6: filter[file]: invalid maxsize: 10x" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestParseConfigSyntaxError(t *testing.T) {
+	_, err := parseConfig([]byte("<logging>\n<filter>\n</logging>"))
+	if errs, ok := err.(ConfigErrors); !ok || len(errs) != 1 || errs[0].Line != 3 {
+		t.Errorf("got %v", err)
+	}
+}
+
+// 配置有误时返回全部错误, 且不改变当前的LogWriter
+func TestLoadConfigurationError(t *testing.T) {
+	w := &testLogWriter{}
+	logger := NewLogger()
+	logger.SetLogWriter("test", w)
+
+	err := logger.LoadConfigurationFromReader(strings.NewReader(memoryConfig("a", "LOUD", "b", "NOISY")))
+	if errs, ok := err.(ConfigErrors); !ok || len(errs) != 2 {
+		t.Fatalf("expect 2 errors, got %v", err)
+	}
+	logger.Info("kept")
+	if got := w.messages(); len(got) != 1 {
+		t.Errorf("log writers changed after failed load: %v", got)
+	}
+
+	filename := filepath.Join(tempDir(t), "log4j.xml")
+	writeFile(t, filename, memoryConfig("a", "LOUD"))
+	if err := ValidateConfiguration(filename); err == nil {
+		t.Error("expect ValidateConfiguration error")
+	}
+	if err := ValidateConfiguration(filename + ".missing"); err == nil {
+		t.Error("expect error for missing file")
+	}
+}
@@ -3,44 +3,142 @@
 package log4j
 
 import (
+	"bytes"
 	"io"
 	"os"
 )
 
-var stdout io.Writer = os.Stdout
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// 彩色输出模式
+const (
+	ColorAuto   = "auto" // 输出到终端时彩色, 设置了环境变量NO_COLOR时不彩色
+	ColorAlways = "true"
+	ColorNever  = "false"
+)
+
+const colorReset = "\x1b[0m"
+
+// 各级别的颜色: TRACE灰, DEBUG青, INFO绿, WARNING黄, ERROR红, FATAL红色加粗
+var levelColors = [...]string{"\x1b[90m", "\x1b[36m", "\x1b[32m", "\x1b[33m", "\x1b[31m", "\x1b[1;31m"}
 
 type ConsoleLogWriter struct {
-	rec    chan *logRecord
-	format string
-	level  Level
+	*logChannel
+	closeCh chan bool // run()写完缓冲区中的日志后关闭
+
+	logFormat
+	level Level
+
+	out, errOut        io.Writer
+	colorOut, colorErr bool // 输出到out、errOut时是否彩色
+	colorLine          bool // 整行彩色, 否则只有级别彩色
+	hasStderrLevel     bool
+	stderrLevel        Level
 }
 
+// format为JsonFormat("json")时每条日志输出一行json
 func (p *ConsoleLogWriter) SetFormat(format string) {
-	p.format = format
+	p.setFormat(format)
 }
 
 func NewConsoleLogWriter(level Level) *ConsoleLogWriter {
+	return NewConsoleLogWriterTo(level, stdout)
+}
+
+// 输出到指定的io.Writer, 如测试中的bytes.Buffer(需自行保证并发安全)
+func NewConsoleLogWriterTo(level Level, out io.Writer) *ConsoleLogWriter {
 	writer := &ConsoleLogWriter{
-		rec:    make(chan *logRecord, LogBufferLength),
-		format: "[%D %T] [%L] (%S) %M",
-		level:  level,
+		logChannel: newLogChannel(LogBufferLength),
+		closeCh:    make(chan bool),
+		logFormat:  logFormat{format: "[%D %T] [%L] (%S) %M"},
+		level:      level,
+		out:        out,
+		errOut:     stderr,
 	}
-	go writer.run(stdout)
+	writer.SetColor(ColorAuto)
+	go writer.run()
 	return writer
 }
 
-func (p *ConsoleLogWriter) run(out io.Writer) {
-	for rec := range p.rec {
+func (p *ConsoleLogWriter) run() {
+	defer close(p.closeCh)
+
+	for rec := range p.logChannel.ch {
+		p.write(rec)
+
+		if notice := p.logChannel.droppedNotice(); notice != nil {
+			p.write(notice)
+		}
+	}
+}
+
+func (p *ConsoleLogWriter) write(rec *LogRecord) {
+	out, color := p.out, p.colorOut
+	if rec != nil && p.hasStderrLevel && rec.Level >= p.stderrLevel {
+		out, color = p.errOut, p.colorErr
+	}
+	if !color || rec == nil || p.format == JsonFormat {
 		_, _ = fPrintFormatLog(out, p.format, rec)
+		return
+	}
+
+	buf := bytesBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bytesBufferPool.Put(buf)
+
+	levelColor := levelColors[rec.Level]
+	if p.colorLine {
+		buf.WriteString(levelColor)
+		formatColorLogRecord(buf, p.format, rec, "")
+		if bs := buf.Bytes(); len(bs) > len(levelColor) && bs[len(bs)-1] == '\n' {
+			buf.Truncate(len(bs) - 1)
+			buf.WriteString(colorReset + "\n")
+		} else {
+			buf.Reset() // 格式为空
+		}
+	} else {
+		formatColorLogRecord(buf, p.format, rec, levelColor)
+	}
+	_, _ = out.Write(buf.Bytes())
+}
+
+// mode: ColorAuto、ColorAlways、ColorNever
+func (p *ConsoleLogWriter) SetColor(mode string) {
+	auto := mode == ColorAuto && os.Getenv("NO_COLOR") == ""
+	p.colorOut = mode == ColorAlways || (auto && isTerminal(p.out))
+	p.colorErr = mode == ColorAlways || (auto && isTerminal(p.errOut))
+}
+
+// 彩色输出时整行使用级别的颜色, 而不只是级别
+func (p *ConsoleLogWriter) SetColorLine(colorLine bool) {
+	p.colorLine = colorLine
+}
+
+// 级别不低于lvl的日志输出到stderr, 如WARNING
+func (p *ConsoleLogWriter) SetStderrLevel(lvl Level) {
+	p.hasStderrLevel, p.stderrLevel = true, lvl
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
 	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-func (p *ConsoleLogWriter) LogWrite(rec *logRecord) {
-	p.rec <- rec
+func (p *ConsoleLogWriter) LogWrite(rec *LogRecord) {
+	p.logChannel.put(rec)
 }
 
+// 等待run()将缓冲区中的日志全部输出后返回, Fatal退出进程前不会丢失日志
 func (p *ConsoleLogWriter) Close() {
-	close(p.rec)
+	p.logChannel.close()
+	<-p.closeCh
 }
 
 func (p *ConsoleLogWriter) IsPrivate() bool {
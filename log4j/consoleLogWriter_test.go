@@ -0,0 +1,115 @@
+package log4j
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestConsoleColorMode(t *testing.T) {
+	// /dev/null是字符设备, 按终端处理
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer devNull.Close()
+
+	cases := []struct {
+		desc    string
+		mode    string
+		noColor string
+		out     *os.File // nil时输出到bytes.Buffer
+		want    bool
+	}{
+		{"auto, not terminal", ColorAuto, "", nil, false},
+		{"auto, terminal", ColorAuto, "", devNull, true},
+		{"auto, terminal, NO_COLOR", ColorAuto, "1", devNull, false},
+		{"always, not terminal", ColorAlways, "", nil, true},
+		{"always, NO_COLOR", ColorAlways, "1", nil, true},
+		{"never, terminal", ColorNever, "", devNull, false},
+	}
+	for _, c := range cases {
+		setEnv(t, "NO_COLOR", c.noColor)
+		w := NewConsoleLogWriterTo(INFO, &bytes.Buffer{})
+		if c.out != nil {
+			w.out = c.out
+		}
+		w.SetColor(c.mode)
+		if w.colorOut != c.want {
+			t.Errorf("%s: colorOut = %v, want %v", c.desc, w.colorOut, c.want)
+		}
+		w.Close()
+	}
+}
+
+func TestConsoleColorOutput(t *testing.T) {
+	cases := []struct {
+		format    string
+		colorLine bool
+		want      string
+	}{
+		{"%L %M", false, "\x1b[33mWARN\x1b[0m m\n"},
+		{"%L %M", true, "\x1b[33mWARN m\x1b[0m\n"},
+		{"[%-6L] %M", false, "[\x1b[33mWARN  \x1b[0m] m\n"},
+		{"%M", false, "m\n"},
+		{"", true, ""},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		w := NewConsoleLogWriterTo(INFO, out)
+		w.SetFormat(c.format)
+		w.SetColor(ColorAlways)
+		w.SetColorLine(c.colorLine)
+		w.LogWrite(&LogRecord{Level: WARNING, Message: "m"})
+		w.Close()
+		if out.String() != c.want {
+			t.Errorf("%q colorLine %v: got %q, want %q", c.format, c.colorLine, out.String(), c.want)
+		}
+	}
+
+	// json格式不彩色
+	out := &bytes.Buffer{}
+	w := NewConsoleLogWriterTo(INFO, out)
+	w.SetFormat(JsonFormat)
+	w.SetColor(ColorAlways)
+	w.LogWrite(&LogRecord{Level: ERROR, Message: "m"})
+	w.Close()
+	if bytes.Contains(out.Bytes(), []byte("\x1b[")) {
+		t.Errorf("json output colored: %q", out.String())
+	}
+}
+
+func TestConsoleStderrLevel(t *testing.T) {
+	errOut := &bytes.Buffer{}
+	defer func(w io.Writer) { stderr = w }(stderr)
+	stderr = errOut
+
+	out := &bytes.Buffer{}
+	w := NewConsoleLogWriterTo(TRACE, out)
+	w.SetFormat("%L %M")
+	w.SetStderrLevel(WARNING)
+	for _, lvl := range []Level{DEBUG, INFO, WARNING, ERROR, FATAL} {
+		w.LogWrite(&LogRecord{Level: lvl, Message: lvl.String()})
+	}
+	w.LogWrite(nil) // 空行输出到stdout
+	w.Close()
+
+	if got := out.String(); got != "DEBG DEBG\nINFO INFO\n\n" {
+		t.Errorf("stdout: %q", got)
+	}
+	if got := errOut.String(); got != "WARN WARN\nEROR EROR\nFATL FATL\n" {
+		t.Errorf("stderr: %q", got)
+	}
+
+	// 未设置时全部输出到stdout
+	out.Reset()
+	errOut.Reset()
+	w = NewConsoleLogWriterTo(TRACE, out)
+	w.SetFormat("%L")
+	w.LogWrite(&LogRecord{Level: ERROR})
+	w.Close()
+	if out.String() != "EROR\n" || errOut.Len() != 0 {
+		t.Errorf("without stderrLevel: stdout %q, stderr %q", out.String(), errOut.String())
+	}
+}
@@ -0,0 +1,110 @@
+package log4j
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// 日志上下文在context.Context中的key
+type logContextKey struct{}
+
+var (
+	contextKeyLock sync.RWMutex
+	contextKeyList []registeredContextKey
+)
+
+type registeredContextKey struct {
+	name string
+	key  interface{}
+}
+
+// 在ctx中存入日志上下文(traceId, requestId, userId等), kvs为 key, value 成对出现; 同名key覆盖旧值
+//
+//	ctx = log4j.ContextWith(ctx, "traceId", traceId)
+//	log4j.InfoCtx(ctx, "query order:%d", orderId) // 格式: [%D %T] [%L] [%X{traceId}] (%S) %M
+func ContextWith(ctx context.Context, kvs ...string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	old, _ := ctx.Value(logContextKey{}).([]Field)
+
+	values := make([]Field, len(old), len(old)+len(kvs)/2)
+	copy(values, old)
+
+	for i := 0; i+1 < len(kvs); i += 2 {
+		replaced := false
+		for j := range values {
+			if values[j].Key == kvs[i] {
+				values[j].Value, replaced = kvs[i+1], true
+				break
+			}
+		}
+		if !replaced {
+			values = append(values, Field{Key: kvs[i], Value: kvs[i+1]})
+		}
+	}
+	return context.WithValue(ctx, logContextKey{}, values)
+}
+
+// 业务已用自定义key把traceId等存入ctx时, 注册后日志中可用%X{name}输出ctx.Value(key); 与ContextWith存入的同名时输出后者
+func RegisterContextKey(name string, key interface{}) {
+	contextKeyLock.Lock()
+	defer contextKeyLock.Unlock()
+
+	for i := range contextKeyList {
+		if contextKeyList[i].name == name {
+			contextKeyList[i].key = key
+			return
+		}
+	}
+	contextKeyList = append(contextKeyList, registeredContextKey{name: name, key: key})
+}
+
+// ContextWith存入的值在前; 注册的key与其同名时, 以ContextWith存入的值为准
+func contextValues(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	values, _ := ctx.Value(logContextKey{}).([]Field)
+
+	contextKeyLock.RLock()
+	defer contextKeyLock.RUnlock()
+
+	if len(contextKeyList) == 0 {
+		return values
+	}
+
+	merged := values
+	for _, registered := range contextKeyList {
+		if hasField(values, registered.name) {
+			continue
+		}
+		if value := ctx.Value(registered.key); value != nil {
+			if len(merged) == len(values) {
+				merged = append(make([]Field, 0, len(values)+len(contextKeyList)), values...)
+			}
+			merged = append(merged, Field{Key: registered.name, Value: fmt.Sprint(value)})
+		}
+	}
+	return merged
+}
+
+func hasField(fields []Field, key string) bool {
+	for i := range fields {
+		if fields[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// logBuffer实现了 Context() context.Context 时, 取出其中的日志上下文
+func logBufferEntry(logBuffer LogBuffer) *Entry {
+	if withContext, ok := logBuffer.(interface{ Context() context.Context }); ok {
+		if ctx := withContext.Context(); ctx != nil {
+			return &Entry{ctx: ctx}
+		}
+	}
+	return nil
+}
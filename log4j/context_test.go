@@ -0,0 +1,49 @@
+package log4j
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+type (
+	testTraceKey struct{}
+	testUserKey  struct{}
+)
+
+func TestContextValues(t *testing.T) {
+	contextKeyLock.Lock()
+	old := contextKeyList
+	contextKeyList = nil
+	contextKeyLock.Unlock()
+	defer func() {
+		contextKeyLock.Lock()
+		contextKeyList = old
+		contextKeyLock.Unlock()
+	}()
+
+	RegisterContextKey("traceId", testTraceKey{})
+	RegisterContextKey("userId", testUserKey{})
+
+	ctx := context.WithValue(context.Background(), testTraceKey{}, "registered")
+	ctx = context.WithValue(ctx, testUserKey{}, 7)
+	ctx = ContextWith(ctx, "traceId", "t-1", "spanId", "s-1")
+	ctx = ContextWith(ctx, "spanId", "s-2")
+
+	// 同名时以ContextWith存入的为准, 只输出一次
+	want := []Field{{"traceId", "t-1"}, {"spanId", "s-2"}, {"userId", "7"}}
+	if got := contextValues(ctx); !reflect.DeepEqual(got, want) {
+		t.Errorf("contextValues = %v, want %v", got, want)
+	}
+
+	out := &bytes.Buffer{}
+	formatLogRecord(out, "%X{traceId}|%X", &LogRecord{Context: contextValues(ctx)})
+	if got, want := out.String(), "t-1|traceId=t-1 spanId=s-2 userId=7\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := contextValues(context.Background()); len(got) != 0 {
+		t.Errorf("empty ctx: %v", got)
+	}
+}
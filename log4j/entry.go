@@ -0,0 +1,129 @@
+package log4j
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 携带结构化字段的日志入口, 字段随LogRecord传递给每个LogWriter, 格式化时用%K输出
+//
+//	log4j.With("orderId", orderId, "userId", userId).Info("create order success")
+type Entry struct {
+	logger *Logger
+	fields []Field
+	ctx    context.Context
+	tags   []string // 同时写入的多个tag
+}
+
+// kvs为 key, value 成对出现; key不是string时用fmt.Sprint转换
+func With(kvs ...interface{}) *Entry {
+	return (&Entry{logger: defaultLogger}).With(kvs...)
+}
+
+// 返回新的Entry, 不修改原Entry的字段
+func (e *Entry) With(kvs ...interface{}) *Entry {
+	fields := make([]Field, len(e.fields), len(e.fields)+(len(kvs)+1)/2)
+	copy(fields, e.fields)
+
+	for i := 0; i < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		if !ok {
+			key = fmt.Sprint(kvs[i])
+		}
+		if i+1 < len(kvs) {
+			fields = append(fields, Field{Key: key, Value: kvs[i+1]})
+		} else {
+			fields = append(fields, Field{Key: key, Value: "!MISSING"}) // 缺少value
+		}
+	}
+	return &Entry{logger: e.logger, fields: fields, ctx: e.ctx, tags: e.tags}
+}
+
+// 日志记录时从ctx取出ContextWith存入的值及RegisterContextKey注册的值, 格式化时用%X{key}输出
+func WithContext(ctx context.Context) *Entry {
+	return &Entry{logger: defaultLogger, ctx: ctx}
+}
+
+func (e *Entry) WithContext(ctx context.Context) *Entry {
+	return &Entry{logger: e.logger, fields: e.fields, ctx: ctx, tags: e.tags}
+}
+
+// 日志同时按多个tag路由, 如 log4j.Tags("order", "audit").Info(...) 同时写入order与audit的私有LogWriter;
+// 与InfoTag等指定的tag合并, LogRecord.Tag为第一个tag
+func Tags(tags ...string) *Entry {
+	return (&Entry{logger: defaultLogger}).Tags(tags...)
+}
+
+func (e *Entry) Tags(tags ...string) *Entry {
+	merged := make([]string, 0, len(e.tags)+len(tags))
+	merged = append(append(merged, e.tags...), tags...)
+	return &Entry{logger: e.logger, fields: e.fields, ctx: e.ctx, tags: merged}
+}
+
+func (e *Entry) fill(rec *LogRecord) {
+	if e != nil {
+		rec.Fields = e.fields
+		rec.Context = contextValues(e.ctx)
+	}
+}
+
+func (e *Entry) Trace(arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(TRACE, false, "", e, arg0, args...)
+}
+
+func (e *Entry) TraceTag(tag string, arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(TRACE, false, tag, e, arg0, args...)
+}
+
+func (e *Entry) Debug(arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(DEBUG, false, "", e, arg0, args...)
+}
+
+func (e *Entry) DebugTag(tag string, arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(DEBUG, false, tag, e, arg0, args...)
+}
+
+func (e *Entry) Info(arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(INFO, false, "", e, arg0, args...)
+}
+
+func (e *Entry) InfoTag(tag string, arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(INFO, false, tag, e, arg0, args...)
+}
+
+func (e *Entry) Warn(arg0 interface{}, args ...interface{}) error {
+	return errors.New(e.logger.addLogArgs(WARNING, false, "", e, arg0, args...))
+}
+
+func (e *Entry) WarnTag(tag string, arg0 interface{}, args ...interface{}) error {
+	return errors.New(e.logger.addLogArgs(WARNING, false, tag, e, arg0, args...))
+}
+
+func (e *Entry) Error(arg0 interface{}, args ...interface{}) error {
+	return errors.New(e.logger.addLogArgs(ERROR, false, "", e, arg0, args...))
+}
+
+func (e *Entry) ErrorTag(tag string, arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(ERROR, false, tag, e, arg0, args...)
+}
+
+// error log with stack info
+func (e *Entry) ErrorStack(arg0 interface{}, args ...interface{}) error {
+	return errors.New(e.logger.addLogArgs(ERROR, true, "", e, arg0, args...))
+}
+
+func (e *Entry) ErrorTagStack(tag string, arg0 interface{}, args ...interface{}) error {
+	return errors.New(e.logger.addLogArgs(ERROR, true, tag, e, arg0, args...))
+}
+
+// fatal log with stack info, 之后关闭所有LogWriter并退出进程
+func (e *Entry) Fatal(arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(FATAL, true, "", e, arg0, args...)
+	e.logger.exit()
+}
+
+func (e *Entry) FatalTag(tag string, arg0 interface{}, args ...interface{}) {
+	e.logger.addLogArgs(FATAL, true, tag, e, arg0, args...)
+	e.logger.exit()
+}
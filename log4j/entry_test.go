@@ -0,0 +1,102 @@
+package log4j
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// 记录收到的日志, 用于检查Logger的输出
+type testLogWriter struct {
+	level   Level
+	private bool
+
+	lock sync.Mutex
+	recs []*LogRecord
+}
+
+func (w *testLogWriter) LogWrite(rec *LogRecord) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.recs = append(w.recs, rec)
+}
+
+func (w *testLogWriter) Close() {
+}
+
+func (w *testLogWriter) IsPrivate() bool {
+	return w.private
+}
+
+func (w *testLogWriter) GetLevel() Level {
+	return w.level
+}
+
+func (w *testLogWriter) records() []*LogRecord {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]*LogRecord(nil), w.recs...)
+}
+
+func (w *testLogWriter) messages() []string {
+	messages := make([]string, 0)
+	for _, rec := range w.records() {
+		if rec != nil {
+			messages = append(messages, rec.Message)
+		}
+	}
+	return messages
+}
+
+func TestWithFields(t *testing.T) {
+	w := &testLogWriter{}
+	logger := NewLogger()
+	logger.SetLogWriter("test", w)
+
+	base := logger.With("orderId", 42)
+	base.With("user", "tom smith", 7, "seven", "odd").Info("created")
+	base.Info("base")
+
+	recs := w.records()
+	if len(recs) != 2 {
+		t.Fatalf("got %d records", len(recs))
+	}
+	want := []Field{{"orderId", 42}, {"user", "tom smith"}, {"7", "seven"}, {"odd", "!MISSING"}}
+	if !reflect.DeepEqual(recs[0].Fields, want) {
+		t.Errorf("fields %v, want %v", recs[0].Fields, want)
+	}
+	// With返回新的Entry, 不修改base
+	if want := []Field{{"orderId", 42}}; !reflect.DeepEqual(recs[1].Fields, want) {
+		t.Errorf("base fields %v, want %v", recs[1].Fields, want)
+	}
+
+	out := &bytes.Buffer{}
+	formatLogRecord(out, "%M %K", recs[0])
+	if got, want := out.String(), "created orderId=42 user=\"tom smith\" 7=seven odd=!MISSING\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteFields(t *testing.T) {
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{"plain", "k=plain"},
+		{"", `k=""`},
+		{"a b", `k="a b"`},
+		{"a=b", `k="a=b"`},
+		{`say "hi"`, `k="say \"hi\""`},
+		{"line\nbreak", `k="line\nbreak"`},
+		{3.5, "k=3.5"},
+		{nil, "k=<nil>"},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		writeFields(out, []Field{{"k", c.value}})
+		if out.String() != c.want {
+			t.Errorf("writeFields(%#v) = %s, want %s", c.value, out.String(), c.want)
+		}
+	}
+}
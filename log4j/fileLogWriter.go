@@ -3,34 +3,50 @@
 package log4j
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // This log writer sends output to a file
 type FileLogWriter struct {
+	*logChannel
+
 	level Level
 	tag   string
 
-	logRecordCh chan *logRecord
-	closeCh     chan bool
+	closeCh chan bool // writeLog()写完缓冲区中的日志后关闭
+
+	// Reload时交接同一文件: 旧LogWriter写完缓冲区前(prevClosed未关闭)新LogWriter不切割文件,
+	// 旧LogWriter被替换后(draining为1)也不再切割, 避免一方切割后另一方写入备份文件; 交接期间文件可能超过maxlines、maxsize
+	prevClosed chan bool
+	draining   int32
 
 	// for del file
-	timeTicker *time.Ticker
+	timeTicker  *time.Ticker
+	retentionCh chan bool
+	delFileStop chan bool
 
 	// The opened file
 	filename string
 	file     *os.File
 
 	// The logging format
-	format string
+	logFormat
 
 	// Rotate at line count
-	maxLines int
-	curLines int
+	maxLines     int
+	curLines     int
+	linesCounted bool // 是否已统计打开前文件中的行数
 
 	// Rotate at size
 	maxSize int
@@ -39,8 +55,13 @@ type FileLogWriter struct {
 	// Rotate daily
 	daily bool
 
-	// create file ymd
-	ymd string
+	// 按时间切割的周期(RotateMinute等), 及切割文件名的时间后缀格式(Go time layout)
+	rotatePeriod  string
+	rotatePattern string
+
+	// 当前文件所属周期的时间后缀, 如按天切割时为yyyyMMdd; 由openTime计算
+	ymd      string
+	openTime time.Time
 
 	// Keep old logFiles (.001, .002, etc)
 	rotate bool
@@ -48,24 +69,42 @@ type FileLogWriter struct {
 	private bool
 
 	keepDay int64
+
+	// 最多保留的切割文件数, 切割文件与正在写的文件总大小上限
+	maxBackups   int
+	maxTotalSize int64
+
+	// 切割后的文件压缩方式, 目前只支持gzip; 压缩在后台进行, Close时等待完成
+	compress   string
+	compressWg sync.WaitGroup
+
+	// 正在压缩的文件数; 大于0时不清理切割文件(避免删除正在压缩的文件, 或同时统计压缩前后的文件), 压缩完成后再通知清理
+	compressing int32
 }
 
+const (
+	CompressGzip = "gzip"
+	gzipSuffix   = ".gz"
+)
+
+// 按时间切割的周期
+const (
+	RotateMinute = "minute"
+	RotateHour   = "hour"
+	RotateDay    = "day"
+	RotateWeek   = "week"
+)
+
 func NewFileLogWriter(tag string, level Level, filename string, rotate bool, keepDay int64) (*FileLogWriter, error) {
 	writer := &FileLogWriter{
-		tag:         tag,
-		level:       level,
-		logRecordCh: make(chan *logRecord, LogBufferLength),
-		closeCh:     make(chan bool),
-		filename:    filename,
-		format:      "[%D %T] [%L] (%S) %M",
-		rotate:      rotate,
-		keepDay:     keepDay,
-	}
-
-	// 设置了日志保存天数，定时删除过期日志
-	if writer.keepDay > 0 {
-		writer.timeTicker = time.NewTicker(time.Second * 60)
-		go writer.delFile()
+		tag:        tag,
+		level:      level,
+		logChannel: newLogChannel(LogBufferLength),
+		closeCh:    make(chan bool),
+		filename:   filename,
+		logFormat:  logFormat{format: "[%D %T] [%L] (%S) %M"},
+		rotate:     rotate,
+		keepDay:    keepDay,
 	}
 
 	// try open log file
@@ -73,6 +112,12 @@ func NewFileLogWriter(tag string, level Level, filename string, rotate bool, kee
 		return nil, fmt.Errorf("fileLogWriter[%s], openFile:%s fail, err:%s", tag, filename, err)
 	}
 
+	// 设置了日志保存天数、保留个数或总大小时, 定时删除旧日志
+	writer.timeTicker = time.NewTicker(time.Second * 60)
+	writer.retentionCh = make(chan bool, 1)
+	writer.delFileStop = make(chan bool)
+	go writer.delFile()
+
 	go writer.writeLog()
 
 	printlnIO(os.Stdout, "INFO", "fileLogWriter[%s], create success, filename:%s", tag, filename)
@@ -80,15 +125,19 @@ func NewFileLogWriter(tag string, level Level, filename string, rotate bool, kee
 }
 
 // This is the FileLogWriter's output method
-func (w *FileLogWriter) LogWrite(rec *logRecord) {
-	w.logRecordCh <- rec
+func (w *FileLogWriter) LogWrite(rec *LogRecord) {
+	w.logChannel.put(rec)
 }
 
 func (w *FileLogWriter) Close() {
-	close(w.logRecordCh)
+	w.timeTicker.Stop()
+	close(w.delFileStop)
+
+	w.logChannel.close()
 	printlnIO(os.Stdout, "INFO", "fileLogWriter[%s] closed log channel", w.tag)
 
 	<-w.closeCh // 等待 writeLog() 将日志全部写完后return
+	w.compressWg.Wait()
 	printlnIO(os.Stdout, "INFO", "fileLogWriter[%s] is closed", w.tag)
 }
 
@@ -100,41 +149,73 @@ func (w *FileLogWriter) writeLog() {
 			printlnIO(os.Stdout, "INFO", "fileLogWrite[%s], close log file:%s, err:%+v", w.tag, w.filename, err)
 			w.file = nil
 		}
-		w.closeCh <- true
+		close(w.closeCh)
 	}()
 
 	for {
-		logRecord, isAlive := <-w.logRecordCh
+		logRecord, isAlive := <-w.logChannel.ch
 		if !isAlive {
 			printlnIO(os.Stdout, "INFO", "fileLogWriter[%s] log channel is empty", w.tag)
 			return
 		}
 
-		if w.rotate && w.file != nil {
-			w.tryMoveFile()
+		w.write(logRecord)
+
+		if notice := w.logChannel.droppedNotice(); notice != nil {
+			w.write(notice)
 		}
+	}
+}
 
-		// 写入日志
-		size, err := 0, error(nil)
-		if w.file != nil {
-			size, err = fPrintFormatLog(w.file, w.format, logRecord)
-		} else {
-			// 程序启动后file是不为空的(执行openFile()失败,主程序会启动失败)；如果运行中file为空，可能是切割日志时关闭了file又无法重新打开
-			size, err = fPrintFormatLog(os.Stdout, w.format, logRecord)
+func (w *FileLogWriter) write(logRecord *LogRecord) {
+	if w.rotate && w.file != nil && w.canRotate() {
+		if w.maxLines > 0 && !w.linesCounted {
+			w.countLines()
 		}
+		w.tryMoveFile()
+	}
 
-		if err != nil {
-			printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] fmt.Fprint fail, err:%s", w.tag, err.Error())
+	// 写入日志
+	size, err := 0, error(nil)
+	if w.file != nil {
+		size, err = fPrintFormatLog(w.file, w.format, logRecord)
+	} else {
+		// 程序启动后file是不为空的(执行openFile()失败,主程序会启动失败)；如果运行中file为空，可能是切割日志时关闭了file又无法重新打开
+		size, err = fPrintFormatLog(os.Stdout, w.format, logRecord)
+	}
 
-		} else if w.rotate {
-			if w.maxLines > 0 {
-				w.curLines++
-			}
-			if w.maxSize > 0 {
-				w.curSize += size
-			}
+	if err != nil {
+		printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] fmt.Fprint fail, err:%s", w.tag, err.Error())
+
+	} else if w.rotate {
+		if w.maxLines > 0 {
+			w.curLines++
+		}
+		if w.maxSize > 0 {
+			w.curSize += size
+		}
+	}
+}
+
+func (w *FileLogWriter) canRotate() bool {
+	if atomic.LoadInt32(&w.draining) == 1 {
+		return false
+	}
+	if w.prevClosed != nil {
+		select {
+		case <-w.prevClosed:
+			w.prevClosed = nil
+		default:
+			return false
 		}
 	}
+	return true
+}
+
+// Reload时由新LogWriter接替prev写同一文件, 须在新LogWriter接收日志前调用
+func (w *FileLogWriter) takeOver(prev *FileLogWriter) {
+	atomic.StoreInt32(&prev.draining, 1)
+	w.prevClosed = prev.closeCh
 }
 
 func (w *FileLogWriter) openFile() error {
@@ -152,12 +233,44 @@ func (w *FileLogWriter) openFile() error {
 	}
 
 	w.file = file
-	w.ymd = getYmd()
+	w.openTime = time.Now()
 	w.curLines = 0
 	w.curSize = 0
+	w.linesCounted = true
+
+	// 重启后继续写已有的文件: 大小从文件获取, 所属周期取文件的修改时间, 行数在需要时再统计
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		w.openTime = info.ModTime()
+		w.curSize = int(info.Size())
+		w.linesCounted = false
+	}
+	w.ymd = w.timeStamp(w.openTime)
 	return nil
 }
 
+// 统计已有文件的行数, 在写日志的goroutine中执行
+func (w *FileLogWriter) countLines() {
+	w.linesCounted = true
+
+	file, err := os.Open(w.filename)
+	if err != nil {
+		printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] count lines of file:%s fail, err:%s", w.tag, w.filename, err.Error())
+		return
+	}
+	defer file.Close()
+
+	bs := bytesPool.Get().([]byte)
+	defer bytesPool.Put(bs)
+
+	for {
+		n, err := file.Read(bs)
+		w.curLines += bytes.Count(bs[:n], newLine)
+		if err != nil {
+			return
+		}
+	}
+}
+
 func (w *FileLogWriter) closeFile() error {
 	if err := w.file.Close(); err == nil {
 		w.file = nil
@@ -170,8 +283,7 @@ func (w *FileLogWriter) closeFile() error {
 // 限制文件大小 或 行数 或 按日切割
 func (w *FileLogWriter) tryMoveFile() {
 
-	ymd := getYmd()
-	if !((w.daily && ymd != w.ymd) || (w.maxLines > 0 && w.curLines >= w.maxLines) || (w.maxSize > 0 && w.curSize >= w.maxSize)) {
+	if !((w.isTimeRotate() && w.timeStamp(time.Now()) != w.ymd) || (w.maxLines > 0 && w.curLines >= w.maxLines) || (w.maxSize > 0 && w.curSize >= w.maxSize)) {
 		return
 	}
 
@@ -180,16 +292,26 @@ func (w *FileLogWriter) tryMoveFile() {
 		return // should not happen
 	}
 
-	for i := 0; i <= 999; i++ {
+	// 从已有切割文件的最大序号之后开始, 旧文件被删除后也不会复用其名称, 保证序号与切割顺序一致
+	start := 0
+	if files, err := w.listRotatedFiles(); err == nil {
+		for _, file := range files {
+			if file.stamp == w.ymd && file.seq >= start {
+				start = file.seq + 1
+			}
+		}
+	}
+
+	for i := start; i <= 999; i++ {
 
-		// 逐个尝试新文件名，stdout.log.ymd or stdout.log.ymd.001 ~ stdout.log.ymd.999
+		// 逐个尝试新文件名，stdout.log.ymd or stdout.log.ymd-001 ~ stdout.log.ymd-999
 		tmpFileName := w.filename + "." + w.ymd
 		if i > 0 {
 			tmpFileName += fmt.Sprintf("-%03d", i)
 		}
 
-		// 检查文件存在; 不存在, 把当前log改名字； stdout.log ===> stdout.log.ymd[.001]
-		if isExist := w.isFileExist(tmpFileName); !isExist {
+		// 检查文件存在(包括压缩后的); 不存在, 把当前log改名字； stdout.log ===> stdout.log.ymd[.001]
+		if isExist := w.isFileExist(tmpFileName) || w.isFileExist(tmpFileName+gzipSuffix); !isExist {
 
 			if err := w.file.Close(); err != nil {
 				// should not happen; 后续使用输出流写日志
@@ -201,6 +323,12 @@ func (w *FileLogWriter) tryMoveFile() {
 
 				if err := os.Rename(w.filename, tmpFileName); err != nil {
 					printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] rename file fail, err:%s", w.tag, err.Error())
+				} else if w.compress == CompressGzip {
+					w.compressWg.Add(1)
+					atomic.AddInt32(&w.compressing, 1)
+					go w.gzipFile(tmpFileName) // 压缩完成后通知清理
+				} else {
+					w.notifyRetention()
 				}
 
 				// 无论是否rename成功，再次打开文件(创建/追加)
@@ -213,48 +341,262 @@ func (w *FileLogWriter) tryMoveFile() {
 	}
 }
 
+// 通知检查保留个数及总大小
+func (w *FileLogWriter) notifyRetention() {
+	select {
+	case w.retentionCh <- true:
+	default:
+	}
+}
+
+// 压缩到临时文件后再改名, 保证不会出现不完整的.gz文件; 成功后删除原文件
+func (w *FileLogWriter) gzipFile(filePath string) {
+	defer func() {
+		atomic.AddInt32(&w.compressing, -1)
+		w.notifyRetention()
+		w.compressWg.Done()
+	}()
+
+	gzFilePath := filePath + gzipSuffix
+	tmpFilePath := gzFilePath + ".tmp"
+
+	err := func() error {
+		src, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		dst, err := os.OpenFile(tmpFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+		if err != nil {
+			return err
+		}
+
+		gzipWriter := gzip.NewWriter(dst)
+		if _, err = io.Copy(gzipWriter, src); err == nil {
+			err = gzipWriter.Close()
+		}
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+
+		// 保留原文件的修改时间, keepDay按切割时间计算
+		if info, err := src.Stat(); err == nil {
+			_ = os.Chtimes(tmpFilePath, info.ModTime(), info.ModTime())
+		}
+		return os.Rename(tmpFilePath, gzFilePath)
+	}()
+
+	if err != nil {
+		_ = os.Remove(tmpFilePath)
+		printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] gzip file:%s fail, err:%s", w.tag, filePath, err.Error())
+		return
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] remove file:%s after gzip fail, err:%s", w.tag, filePath, err.Error())
+	}
+}
+
 func (w *FileLogWriter) isFileExist(filePath string) bool {
 	fileInfo, err := os.Lstat(filePath)
 	return err == nil && fileInfo != nil
 }
 
-// 清除n天前的日志
+// 切割产生的文件: filename.<时间后缀>[-NNN][.gz]
+type rotatedFile struct {
+	path    string
+	stamp   string
+	time    time.Time // 由stamp解析
+	seq     int
+	size    int64
+	modTime time.Time
+}
+
+// 定时及每次切割后清理切割产生的文件
 func (w *FileLogWriter) delFile() {
 
 	for {
-		<-w.timeTicker.C
+		select {
+		case <-w.timeTicker.C:
+		case <-w.retentionCh:
+		case <-w.delFileStop:
+			return
+		}
 
-		// log文件夹位置
-		pathIndex := strings.LastIndex(w.filename, "/")
-		path := w.filename[0:pathIndex]
+		if w.keepDay <= 0 && w.maxBackups <= 0 && w.maxTotalSize <= 0 {
+			continue
+		}
+		if atomic.LoadInt32(&w.compressing) > 0 {
+			continue // 压缩完成后会再次通知
+		}
 
-		if folder, err := ioutil.ReadDir(path); err != nil {
-			printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] readDir:%s fail, err:%s", w.tag, path, err.Error())
-			return
+		files, err := w.listRotatedFiles()
+		if err != nil {
+			printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] list rotated files fail, err:%s", w.tag, err.Error())
+			continue
+		}
+
+		for _, file := range w.expiredFiles(files) {
+			if err := os.Remove(file.path); err != nil {
+				printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] remove:%s fail, err:%s", w.tag, file.path, err.Error())
+			} else {
+				printlnIO(os.Stdout, "INFO", "fileLogWriter[%s] remove:%s success", w.tag, file.path)
+			}
+		}
+	}
+}
+
+// 超过keepDay天, 超过maxBackups个, 或(含正在写的文件)总大小超过maxTotalSize时, 从最旧的开始删除
+func (w *FileLogWriter) expiredFiles(files []*rotatedFile) []*rotatedFile {
+	expired := make([]*rotatedFile, 0)
+
+	if w.keepDay > 0 {
+		deadline := time.Now().Add(-time.Duration(w.keepDay) * 24 * time.Hour)
+		kept := make([]*rotatedFile, 0, len(files))
+		for _, file := range files {
+			if file.modTime.Before(deadline) {
+				expired = append(expired, file)
+			} else {
+				kept = append(kept, file)
+			}
+		}
+		files = kept
+	}
+
+	if w.maxBackups > 0 && len(files) > w.maxBackups {
+		n := len(files) - w.maxBackups
+		expired, files = append(expired, files[:n]...), files[n:]
+	}
+
+	if w.maxTotalSize > 0 {
+		totalSize := int64(0)
+		if info, err := os.Stat(w.filename); err == nil {
+			totalSize = info.Size()
+		}
+		for _, file := range files {
+			totalSize += file.size
+		}
+		for len(files) > 0 && totalSize > w.maxTotalSize {
+			totalSize -= files[0].size
+			expired, files = append(expired, files[0]), files[1:]
+		}
+	}
+	return expired
+}
+
+// 只列出本writer切割产生的文件, 按切割顺序从旧到新排序
+func (w *FileLogWriter) listRotatedFiles() ([]*rotatedFile, error) {
+	pathIndex := strings.LastIndex(w.filename, "/")
+	path, base := w.filename[0:pathIndex], w.filename[pathIndex+1:]
+
+	folder, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := w.rotatedLayouts()
+	files := make([]*rotatedFile, 0)
+	for _, info := range folder {
+		if info.IsDir() {
+			continue
+		}
+		if stamp, stampTime, seq, ok := parseRotatedName(base, layouts, info.Name()); ok {
+			files = append(files, &rotatedFile{
+				path:    path + "/" + info.Name(),
+				stamp:   stamp,
+				time:    stampTime,
+				seq:     seq,
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].time.Equal(files[j].time) {
+			return files[i].time.Before(files[j].time)
+		}
+		return files[i].seq < files[j].seq
+	})
+	return files, nil
+}
+
+// 解析 base.<时间后缀>[-NNN][.gz], 时间后缀依次尝试layouts; 不符合格式的返回false
+func parseRotatedName(base string, layouts []string, name string) (stamp string, stampTime time.Time, seq int, ok bool) {
+	if !strings.HasPrefix(name, base+".") {
+		return "", time.Time{}, 0, false
+	}
+	suffix := strings.TrimSuffix(name[len(base)+1:], gzipSuffix)
 
-		} else {
-			timeNow := time.Now().Unix()
-			for _, file := range folder {
-				if !file.IsDir() && file.ModTime().Unix()+86400*w.keepDay < timeNow {
-
-					filePath := path + "/" + file.Name()
-					if filePath == w.filename { // 正在打的日志不删
-						continue
-					}
-
-					if strings.HasPrefix(filePath, w.filename) {
-						if err := os.Remove(filePath); err != nil {
-							printlnIO(os.Stderr, "ERROR", "fileLogWriter[%s] remove:%s fail, err:%s", w.tag, filePath, err.Error())
-						} else {
-							printlnIO(os.Stdout, "INFO", "fileLogWriter[%s] remove:%s success", w.tag, filePath)
-						}
-					}
+	for _, layout := range layouts {
+		if index := strings.LastIndexByte(suffix, '-'); index > 0 && len(suffix)-index == 4 {
+			if seq, err := strconv.Atoi(suffix[index+1:]); err == nil && seq > 0 {
+				if stampTime, err := time.ParseInLocation(layout, suffix[:index], time.Local); err == nil {
+					return suffix[:index], stampTime, seq, true
 				}
 			}
 		}
+		if stampTime, err := time.ParseInLocation(layout, suffix, time.Local); err == nil {
+			return suffix, stampTime, 0, true
+		}
+	}
+	return "", time.Time{}, 0, false
+}
+
+// 识别切割文件的时间后缀格式: 当前格式及各周期的默认格式, 修改rotatePeriod后旧格式的文件仍会被清理;
+// 修改rotatePattern前按旧pattern切割的文件不再被识别, 需手动清理
+func (w *FileLogWriter) rotatedLayouts() []string {
+	layouts := []string{w.timeLayout()}
+	for _, layout := range []string{"20060102", "2006010215", "200601021504"} {
+		if layout != layouts[0] {
+			layouts = append(layouts, layout)
+		}
+	}
+	return layouts
+}
+
+func (w *FileLogWriter) isTimeRotate() bool {
+	return w.daily || w.rotatePeriod != "" || w.rotatePattern != ""
+}
+
+func (w *FileLogWriter) timeLayout() string {
+	if w.rotatePattern != "" {
+		return w.rotatePattern
+	}
+	switch w.rotatePeriod {
+	case RotateMinute:
+		return "200601021504"
+	case RotateHour:
+		return "2006010215"
+	default:
+		return "20060102"
 	}
 }
 
+// t所属周期的时间后缀; 按周切割时为周一的日期, 只设置了rotatePattern时由pattern决定周期
+func (w *FileLogWriter) timeStamp(t time.Time) string {
+	period := w.rotatePeriod
+	if period == "" && w.daily {
+		period = RotateDay
+	}
+
+	switch period {
+	case RotateMinute:
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	case RotateHour:
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotateDay:
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case RotateWeek:
+		t = time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	}
+	return t.Format(w.timeLayout())
+}
+
 func (w *FileLogWriter) IsPrivate() bool {
 	return w.private
 }
@@ -263,8 +605,9 @@ func (w *FileLogWriter) GetLevel() Level {
 	return w.level
 }
 
+// format为JsonFormat("json")时每条日志输出一行json
 func (w *FileLogWriter) SetFormat(format string) *FileLogWriter {
-	w.format = format
+	w.setFormat(format)
 	return w
 }
 
@@ -278,8 +621,23 @@ func (w *FileLogWriter) SetRotateSize(maxsize int) *FileLogWriter {
 	return w
 }
 
+// 按时间切割的周期: RotateMinute、RotateHour、RotateDay、RotateWeek
+func (w *FileLogWriter) SetRotatePeriod(period string) *FileLogWriter {
+	w.rotatePeriod = period
+	w.ymd = w.timeStamp(w.openTime)
+	return w
+}
+
+// 切割文件名的时间后缀格式(Go time layout), 如"2006010215"; 未设置rotatePeriod时按该格式的变化切割
+func (w *FileLogWriter) SetRotatePattern(pattern string) *FileLogWriter {
+	w.rotatePattern = pattern
+	w.ymd = w.timeStamp(w.openTime)
+	return w
+}
+
 func (w *FileLogWriter) SetRotateDaily(daily bool) *FileLogWriter {
 	w.daily = daily
+	w.ymd = w.timeStamp(w.openTime)
 	return w
 }
 
@@ -293,10 +651,24 @@ func (w *FileLogWriter) SetPrivate(private bool) *FileLogWriter {
 	return w
 }
 
-func (w *FileLogWriter) GetFilename() string {
-	return w.filename
+// 最多保留的切割文件数, 0表示不限制
+func (w *FileLogWriter) SetMaxBackups(maxBackups int) *FileLogWriter {
+	w.maxBackups = maxBackups
+	return w
 }
 
-func getYmd() string {
-	return time.Now().Format("20060102")
+// 切割文件与正在写的文件总大小上限(字节), 0表示不限制
+func (w *FileLogWriter) SetMaxTotalSize(maxTotalSize int64) *FileLogWriter {
+	w.maxTotalSize = maxTotalSize
+	return w
+}
+
+// 切割后的文件压缩方式, 空字符串表示不压缩
+func (w *FileLogWriter) SetCompress(compress string) *FileLogWriter {
+	w.compress = compress
+	return w
+}
+
+func (w *FileLogWriter) GetFilename() string {
+	return w.filename
 }
@@ -0,0 +1,424 @@
+package log4j
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRotatedName(t *testing.T) {
+	layouts := (&FileLogWriter{rotatePeriod: RotateHour}).rotatedLayouts()
+
+	cases := []struct {
+		name  string
+		ok    bool
+		stamp string
+		seq   int
+	}{
+		{"app.log.2024010215", true, "2024010215", 0},
+		{"app.log.2024010215-002", true, "2024010215", 2},
+		{"app.log.2024010215-003.gz", true, "2024010215", 3},
+		{"app.log.20240102", true, "20240102", 0},        // 修改rotatePeriod前按天切割的文件
+		{"app.log.20240102-001.gz", true, "20240102", 1}, // 同上, 已压缩
+		{"app.log", false, "", 0},
+		{"app.log.bak", false, "", 0},
+		{"app.log.2024010215-02", false, "", 0},
+		{"app.log.2024013215", false, "", 0},
+		{"other.log.2024010215", false, "", 0},
+		{"app.logx.2024010215", false, "", 0},
+	}
+	for _, c := range cases {
+		stamp, _, seq, ok := parseRotatedName("app.log", layouts, c.name)
+		if ok != c.ok || stamp != c.stamp || seq != c.seq {
+			t.Errorf("parseRotatedName(%q) = %q, %d, %v; want %q, %d, %v", c.name, stamp, seq, ok, c.stamp, c.seq, c.ok)
+		}
+	}
+}
+
+func TestParseRotatedNamePattern(t *testing.T) {
+	layouts := (&FileLogWriter{rotatePattern: "2006-01-02_15"}).rotatedLayouts()
+
+	stamp, stampTime, seq, ok := parseRotatedName("app.log", layouts, "app.log.2024-01-02_15-001")
+	if !ok || stamp != "2024-01-02_15" || seq != 1 {
+		t.Fatalf("got %q, %d, %v", stamp, seq, ok)
+	}
+	if want := time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local); !stampTime.Equal(want) {
+		t.Errorf("stampTime = %s, want %s", stampTime, want)
+	}
+}
+
+func TestExpiredFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log4j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(filename, make([]byte, 100), 0660); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	newFiles := func() []*rotatedFile {
+		// 从旧到新, 与listRotatedFiles的顺序一致
+		return []*rotatedFile{
+			{path: "d10", size: 100, modTime: now.Add(-10 * 24 * time.Hour)},
+			{path: "d5", size: 100, modTime: now.Add(-5 * 24 * time.Hour)},
+			{path: "d2", size: 100, modTime: now.Add(-2 * 24 * time.Hour)},
+			{path: "d1", size: 100, modTime: now.Add(-1 * 24 * time.Hour)},
+		}
+	}
+
+	cases := []struct {
+		desc   string
+		writer *FileLogWriter
+		want   []string
+	}{
+		{"no limit", &FileLogWriter{}, nil},
+		{"keepDay", &FileLogWriter{keepDay: 3}, []string{"d10", "d5"}},
+		{"maxBackups", &FileLogWriter{maxBackups: 1}, []string{"d10", "d5", "d2"}},
+		{"maxTotalSize counts current file", &FileLogWriter{maxTotalSize: 300}, []string{"d10", "d5"}},
+		{"keepDay then maxBackups", &FileLogWriter{keepDay: 3, maxBackups: 1}, []string{"d10", "d5", "d2"}},
+		{"within limits", &FileLogWriter{keepDay: 30, maxBackups: 4, maxTotalSize: 500}, nil},
+	}
+	for _, c := range cases {
+		c.writer.filename = filename
+		got := make([]string, 0)
+		for _, file := range c.writer.expiredFiles(newFiles()) {
+			got = append(got, file.path)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: expired %v, want %v", c.desc, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: expired %v, want %v", c.desc, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestListRotatedFilesAfterPeriodChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log4j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"app.log", "app.log.20240101", "app.log.2024010210", "app.log.2024010209-001.gz", "app.log.tmp"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &FileLogWriter{filename: filepath.Join(dir, "app.log"), rotatePeriod: RotateHour}
+	files, err := w.listRotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"app.log.20240101", "app.log.2024010209-001.gz", "app.log.2024010210"}
+	if len(files) != len(want) {
+		t.Fatalf("got %d files, want %v", len(files), want)
+	}
+	for i, file := range files {
+		if filepath.Base(file.path) != want[i] {
+			t.Errorf("files[%d] = %s, want %s", i, filepath.Base(file.path), want[i])
+		}
+	}
+}
+
+func TestNewFileLogWriterOpenFail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log4j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 目录已存在同名文件, 无法创建日志文件所在目录
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0660); err != nil {
+		t.Fatal(err)
+	}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		if w, err := NewFileLogWriter("test", INFO, filepath.Join(dir, "file", "app.log"), false, 1); err == nil || w != nil {
+			t.Fatalf("expect open fail, got %v, %v", w, err)
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines %d -> %d after failed opens", before, after)
+	}
+}
+
+// 读取日志文件的各行, .gz文件先解压
+func readLogLines(t *testing.T, filename string) []string {
+	t.Helper()
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := io.Reader(file)
+	if strings.HasSuffix(filename, gzipSuffix) {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("%s: %s", filename, err)
+		}
+		reader = zr
+	}
+	lines := make([]string, 0)
+	for scanner := bufio.NewScanner(reader); scanner.Scan(); {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// 目录中的文件名, 排序后返回
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotateGzip(t *testing.T) {
+	dir := tempDir(t)
+	w, err := NewFileLogWriter("test", INFO, filepath.Join(dir, "app.log"), true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetFormat("%M").SetRotateLines(5).SetCompress(CompressGzip)
+	for i := 0; i < 12; i++ {
+		w.LogWrite(testRecord(fmt.Sprintf("line-%d", i)))
+	}
+	w.Close() // 等待压缩完成
+
+	ymd := w.timeStamp(time.Now())
+	first, second := "app.log."+ymd+".gz", "app.log."+ymd+"-001.gz"
+	if got, want := listDir(t, dir), []string{"app.log", second, first}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files %v, want %v", got, want)
+	}
+
+	lines := append(readLogLines(t, filepath.Join(dir, first)), readLogLines(t, filepath.Join(dir, second))...)
+	lines = append(lines, readLogLines(t, filepath.Join(dir, "app.log"))...)
+	for i, line := range lines {
+		if line != fmt.Sprintf("line-%d", i) {
+			t.Fatalf("line %d: %q, all lines %v", i, line, lines)
+		}
+	}
+	if len(lines) != 12 {
+		t.Errorf("got %d lines", len(lines))
+	}
+}
+
+// 压缩完成后才清理: 不删除正在压缩的文件, 也不留下被清理文件的.gz
+func TestRetentionWithGzip(t *testing.T) {
+	dir := tempDir(t)
+	w, err := NewFileLogWriter("test", INFO, filepath.Join(dir, "app.log"), true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetFormat("%M").SetRotateLines(10).SetCompress(CompressGzip).SetMaxBackups(2)
+	for i := 0; i < 65; i++ {
+		w.LogWrite(testRecord(fmt.Sprintf("line-%d", i)))
+	}
+
+	// 第6次切割产生-005, 清理后只保留最新的-004、-005
+	ymd := w.timeStamp(time.Now())
+	want := []string{"app.log", "app.log." + ymd + "-004.gz", "app.log." + ymd + "-005.gz"}
+	got := []string(nil)
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if got = listDir(t, dir); strings.Join(got, ",") == strings.Join(want, ",") {
+			break
+		}
+	}
+	w.Close()
+
+	if got = listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files %v, want %v", got, want)
+	}
+	for i, name := range want[1:] {
+		lines := readLogLines(t, filepath.Join(dir, name))
+		if len(lines) != 10 || lines[0] != fmt.Sprintf("line-%d", 40+i*10) {
+			t.Errorf("%s: %v", name, lines)
+		}
+	}
+}
+
+func TestRetentionSkippedWhileCompressing(t *testing.T) {
+	dir := tempDir(t)
+	filename := filepath.Join(dir, "app.log")
+	for _, name := range []string{"app.log", "app.log.20240101", "app.log.20240102", "app.log.20240102.gz.tmp"} {
+		writeFile(t, filepath.Join(dir, name), "x\n")
+	}
+
+	w := &FileLogWriter{
+		filename:    filename,
+		maxBackups:  1,
+		timeTicker:  time.NewTicker(time.Hour),
+		retentionCh: make(chan bool, 1),
+		delFileStop: make(chan bool),
+		compressing: 1,
+	}
+	go w.delFile()
+	defer func() {
+		w.timeTicker.Stop()
+		close(w.delFileStop)
+	}()
+
+	w.notifyRetention()
+	time.Sleep(50 * time.Millisecond)
+	if got := listDir(t, dir); len(got) != 4 {
+		t.Fatalf("removed while compressing: %v", got)
+	}
+
+	// 压缩完成后通知清理
+	atomic.AddInt32(&w.compressing, -1)
+	w.notifyRetention()
+	want := "app.log,app.log.20240102,app.log.20240102.gz.tmp"
+	for deadline := time.Now().Add(3 * time.Second); strings.Join(listDir(t, dir), ",") != want; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("files %v, want %s", listDir(t, dir), want)
+		}
+	}
+}
+
+func TestTimeStamp(t *testing.T) {
+	wednesday := time.Date(2024, 1, 3, 15, 4, 5, 0, time.Local)
+	sunday := time.Date(2024, 1, 7, 23, 59, 0, 0, time.Local)
+
+	cases := []struct {
+		writer *FileLogWriter
+		t      time.Time
+		want   string
+	}{
+		{&FileLogWriter{daily: true}, wednesday, "20240103"},
+		{&FileLogWriter{rotatePeriod: RotateMinute}, wednesday, "202401031504"},
+		{&FileLogWriter{rotatePeriod: RotateHour}, wednesday, "2024010315"},
+		{&FileLogWriter{rotatePeriod: RotateDay}, wednesday, "20240103"},
+		{&FileLogWriter{rotatePeriod: RotateWeek}, wednesday, "20240101"}, // 周一
+		{&FileLogWriter{rotatePeriod: RotateWeek}, sunday, "20240101"},
+		{&FileLogWriter{rotatePattern: "2006-01-02_15"}, wednesday, "2024-01-03_15"},
+		{&FileLogWriter{rotatePeriod: RotateWeek, rotatePattern: "2006-01-02"}, sunday, "2024-01-01"},
+	}
+	for _, c := range cases {
+		if got := c.writer.timeStamp(c.t); got != c.want {
+			t.Errorf("period %q pattern %q daily %v: got %s, want %s", c.writer.rotatePeriod, c.writer.rotatePattern, c.writer.daily, got, c.want)
+		}
+	}
+}
+
+func TestIsValidRotatePattern(t *testing.T) {
+	for pattern, want := range map[string]bool{"2006010215": true, "2006-01-02_15": true, "2006/01/02": false, "daily": false} {
+		if got := isValidRotatePattern(pattern); got != want {
+			t.Errorf("isValidRotatePattern(%q) = %v", pattern, got)
+		}
+	}
+}
+
+// 所属周期变化后按当前周期的时间后缀命名切割文件, 同一后缀再次切割时加序号
+func TestTimeRotateNames(t *testing.T) {
+	cases := []struct {
+		period, pattern string
+		stamp           string // 模拟打开文件时所属的周期
+		want            []string
+	}{
+		{RotateHour, "", "2024010214", []string{"app.log", "app.log.2024010214", "app.log.2024010214-001"}},
+		{RotateMinute, "", "202401021459", []string{"app.log", "app.log.202401021459", "app.log.202401021459-001"}},
+		{"", "2006-01-02_15", "2024-01-02_14", []string{"app.log", "app.log.2024-01-02_14", "app.log.2024-01-02_14-001"}},
+	}
+	for _, c := range cases {
+		dir := tempDir(t)
+		w := &FileLogWriter{filename: filepath.Join(dir, "app.log"), logFormat: logFormat{format: "%M"}, rotate: true}
+		if err := w.openFile(); err != nil {
+			t.Fatal(err)
+		}
+		w.SetRotatePeriod(c.period).SetRotatePattern(c.pattern)
+
+		for _, message := range []string{"a", "b", "c"} {
+			w.write(testRecord(message))
+			w.ymd = c.stamp
+		}
+		_ = w.file.Close()
+
+		if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("period %q pattern %q: files %v, want %v", c.period, c.pattern, got, c.want)
+			continue
+		}
+		for i, message := range []string{"c", "a", "b"} {
+			if lines := readLogLines(t, filepath.Join(dir, c.want[i])); len(lines) != 1 || lines[0] != message {
+				t.Errorf("%s: %v, want %s", c.want[i], lines, message)
+			}
+		}
+	}
+}
+
+// 重启后继续写已有的文件: 大小、行数及所属周期从已有文件恢复
+func TestResumeAfterRestart(t *testing.T) {
+	yesterday := time.Now().Add(-24 * time.Hour)
+	cases := []struct {
+		desc     string
+		existing string
+		modTime  time.Time
+		setup    func(w *FileLogWriter)
+		rotateAt int // 第几条(从0开始)写入前切割, -1表示不切割
+		rotated  string
+	}{
+		{"size", strings.Repeat("x", 99) + "\n", time.Now(), func(w *FileLogWriter) { w.SetRotateSize(120) }, 2, time.Now().Format("20060102")},
+		{"lines", strings.Repeat("x\n", 8), time.Now(), func(w *FileLogWriter) { w.SetRotateLines(10) }, 2, time.Now().Format("20060102")},
+		{"daily", "x\n", yesterday, func(w *FileLogWriter) { w.SetRotateDaily(true) }, 0, yesterday.Format("20060102")},
+		{"within limits", "x\n", time.Now(), func(w *FileLogWriter) { w.SetRotateLines(10).SetRotateSize(1000).SetRotateDaily(true) }, -1, ""},
+	}
+	for _, c := range cases {
+		dir := tempDir(t)
+		filename := filepath.Join(dir, "app.log")
+		writeFile(t, filename, c.existing)
+		if err := os.Chtimes(filename, c.modTime, c.modTime); err != nil {
+			t.Fatal(err)
+		}
+
+		w := &FileLogWriter{filename: filename, logFormat: logFormat{format: "%M"}, rotate: true}
+		if err := w.openFile(); err != nil {
+			t.Fatal(err)
+		}
+		c.setup(w)
+
+		for i := 0; i < 4; i++ {
+			w.write(testRecord(fmt.Sprintf("%09d", i))) // 每条10字节
+			if rotated := w.isFileExist(filename + "." + c.rotated); c.rotated != "" && rotated != (i >= c.rotateAt) {
+				t.Errorf("%s: after write %d rotated=%v, want rotation before write %d", c.desc, i, rotated, c.rotateAt)
+			}
+		}
+		_ = w.file.Close()
+
+		if c.rotateAt < 0 {
+			if got := listDir(t, dir); len(got) != 1 {
+				t.Errorf("%s: unexpected rotation %v", c.desc, got)
+			}
+			continue
+		}
+		if lines := readLogLines(t, filename); len(lines) != 4-c.rotateAt {
+			t.Errorf("%s: current file %v", c.desc, lines)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package log4j
+
+import (
+	"regexp"
+)
+
+// 包装一个LogWriter, 只写入满足全部条件的日志:
+// Message匹配include且不匹配exclude, Source以includeSource之一开头且不以excludeSource之一开头, 级别不高于maxLevel
+type FilterLogWriter struct {
+	writer LogWriter
+
+	include       *regexp.Regexp
+	exclude       *regexp.Regexp
+	includeSource []string
+	excludeSource []string
+	hasMaxLevel   bool
+	maxLevel      Level
+}
+
+func NewFilterLogWriter(writer LogWriter) *FilterLogWriter {
+	return &FilterLogWriter{writer: writer}
+}
+
+func (w *FilterLogWriter) LogWrite(rec *LogRecord) {
+	if rec == nil || w.accept(rec) {
+		w.writer.LogWrite(rec)
+	}
+}
+
+func (w *FilterLogWriter) accept(rec *LogRecord) bool {
+	if w.hasMaxLevel && rec.Level > w.maxLevel {
+		return false
+	}
+	if len(w.includeSource) > 0 && !hasAnyPrefix(rec.Source, w.includeSource) {
+		return false
+	}
+	if len(w.excludeSource) > 0 && hasAnyPrefix(rec.Source, w.excludeSource) {
+		return false
+	}
+	if w.include != nil && !w.include.MatchString(rec.Message) {
+		return false
+	}
+	if w.exclude != nil && w.exclude.MatchString(rec.Message) {
+		return false
+	}
+	return true
+}
+
+// 与<logger>相同按路径边界匹配, github.com/acme/order不匹配github.com/acme/orderx
+func hasAnyPrefix(source string, prefixes []string) bool {
+	funcName := sourceFuncName(source)
+	for _, prefix := range prefixes {
+		if isPathPrefix(funcName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *FilterLogWriter) Close() {
+	w.writer.Close()
+}
+
+func (w *FilterLogWriter) IsPrivate() bool {
+	return w.writer.IsPrivate()
+}
+
+func (w *FilterLogWriter) GetLevel() Level {
+	return w.writer.GetLevel()
+}
+
+func (w *FilterLogWriter) Unwrap() LogWriter {
+	return w.writer
+}
+
+// 只写入Message匹配include的日志, nil表示不限制
+func (w *FilterLogWriter) SetInclude(include *regexp.Regexp) *FilterLogWriter {
+	w.include = include
+	return w
+}
+
+// 不写入Message匹配exclude的日志, nil表示不限制
+func (w *FilterLogWriter) SetExclude(exclude *regexp.Regexp) *FilterLogWriter {
+	w.exclude = exclude
+	return w
+}
+
+// 只写入Source(包/函数路径)以其中之一开头的日志
+func (w *FilterLogWriter) SetIncludeSource(prefixes []string) *FilterLogWriter {
+	w.includeSource = prefixes
+	return w
+}
+
+func (w *FilterLogWriter) SetExcludeSource(prefixes []string) *FilterLogWriter {
+	w.excludeSource = prefixes
+	return w
+}
+
+// 只写入级别不高于maxLevel的日志, 与LogWriter的级别组成级别范围, 如只要WARNING
+func (w *FilterLogWriter) SetMaxLevel(maxLevel Level) *FilterLogWriter {
+	w.hasMaxLevel, w.maxLevel = true, maxLevel
+	return w
+}
@@ -0,0 +1,93 @@
+package log4j
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFilterAccept(t *testing.T) {
+	w := NewFilterLogWriter(&testLogWriter{}).
+		SetInclude(regexp.MustCompile(`order|pay`)).
+		SetExclude(regexp.MustCompile(`heartbeat`)).
+		SetIncludeSource([]string{"github.com/acme/order", "github.com/acme/pay/"}).
+		SetExcludeSource([]string{"github.com/acme/order/mock"}).
+		SetMaxLevel(WARNING)
+
+	cases := []struct {
+		desc    string
+		level   Level
+		source  string
+		message string
+		want    bool
+	}{
+		{"all match", INFO, "github.com/acme/order.Create:12", "order created", true},
+		{"sub package", WARNING, "github.com/acme/order/db.Save:3", "order saved", true},
+		{"prefix ending with /", INFO, "github.com/acme/pay/api.Do:8", "pay done", true},
+		{"above maxLevel", ERROR, "github.com/acme/order.Create:12", "order fail", false},
+		{"include not matched", INFO, "github.com/acme/order.Create:12", "created", false},
+		{"exclude matched", INFO, "github.com/acme/order.Create:12", "order heartbeat", false},
+		{"source path boundary", INFO, "github.com/acme/orderx.Create:12", "order created", false},
+		{"source not included", INFO, "github.com/acme/user.Get:1", "order of user", false},
+		{"source excluded", INFO, "github.com/acme/order/mock.New:1", "order mock", false},
+		{"source without line", INFO, "github.com/acme/order.Create", "order", true},
+	}
+	for _, c := range cases {
+		rec := &LogRecord{Level: c.level, Source: c.source, Message: c.message}
+		if got := w.accept(rec); got != c.want {
+			t.Errorf("%s: accept = %v, want %v", c.desc, got, c.want)
+		}
+	}
+
+	// 不设置任何条件时全部写入
+	if !NewFilterLogWriter(&testLogWriter{}).accept(&LogRecord{Level: FATAL}) {
+		t.Error("empty filter should accept all")
+	}
+}
+
+// 配置的过滤条件与LogWriter的级别组成级别范围, 过滤在抑制重复日志之前
+func TestFilterChain(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+	err := logger.LoadConfigurationFromReader(strings.NewReader(`<logging>
+<filter enabled="true"><tag>warn</tag><type>memory</type><level>WARNING</level>
+  <property name="maxLevel">WARNING</property>
+  <property name="include">^slow</property>
+  <property name="exclude">ignored$</property>
+  <property name="includeSource">github.com/ZhouJunjun/goLib/log4j</property>
+  <property name="excludeSource">github.com/ZhouJunjun/goLib/log4j.logFilterOther</property>
+  <property name="suppressWindow">1h</property>
+</filter>
+</logging>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("slow info")
+	for i := 0; i < 2; i++ {
+		logger.Warn("slow query") // 第二条被抑制
+	}
+	logger.Warn("slow ignored") // exclude
+	logger.Warn("fast query")   // include
+	logger.Error("slow error")  // maxLevel
+	logFilterOther(logger)      // excludeSource
+	logger.Warn("slow disk")
+
+	if got := memoryMessages(logger, "warn"); strings.Join(got, ",") != "slow query,slow disk" {
+		t.Errorf("got %v", got)
+	}
+
+	logger.lock.RLock()
+	filter, ok := logger.logWriterMap["warn"].(*FilterLogWriter)
+	logger.lock.RUnlock()
+	if !ok {
+		t.Fatalf("expect FilterLogWriter outermost")
+	}
+	if _, ok := filter.Unwrap().(*SuppressLogWriter); !ok {
+		t.Errorf("expect SuppressLogWriter inside FilterLogWriter, got %T", filter.Unwrap())
+	}
+}
+
+func logFilterOther(logger *Logger) {
+	logger.Warn("slow other")
+}
@@ -2,26 +2,146 @@ package log4j
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
+// format设置为json时, 每条日志输出为一行json
+const JsonFormat = "json"
+
+// 格式中的占位符:
+//
+//	%T 时间(15:04:05.000 MST)  %t 时间(15:04)  %D 日期(2006/01/02)  %d 日期(01/02/06)
+//	%D{layout} 按time.Format的layout输出时间, 如%D{2006-01-02T15:04:05.000Z07:00}
+//	%L 级别  %S 调用函数:行号  %s 去掉包路径的%S  %f 函数名  %F 文件名:行号(%F{long}为完整路径)
+//	%M 日志内容(及堆栈)  %B 换行  %K 结构化字段  %X 全部上下文值  %X{key} 单个上下文值
+//	%c 日志的tag  %P 进程id  %H 主机名  %G goroutine id  %% 字符%
+//
+// %与占位符之间可以指定宽度, 不足时补空格: %5L右对齐, %-5L左对齐
+var formatCache sync.Map // format -> []formatPiece
+
 var (
-	sep = []byte{'%'}
+	pid         = os.Getpid()
+	hostname, _ = os.Hostname()
 )
 
-func fPrintFormatLog(w io.Writer, format string, rec *logRecord) (int, error) {
+type formatPiece struct {
+	verb  byte   // 0表示原样输出text
+	text  string // 原样输出的文本, 或占位符{}中的参数
+	width int
+	left  bool
+}
+
+func fPrintFormatLog(w io.Writer, format string, rec *LogRecord) (int, error) {
 
 	out := bytesBufferPool.Get().(*bytes.Buffer)
 	out.Reset()
 	defer bytesBufferPool.Put(out)
 
-	formatLogRecord(out, format, rec)
+	if format == JsonFormat {
+		if rec == nil {
+			return 0, nil // 空行在json格式下无意义
+		}
+		formatJsonLogRecord(out, rec)
+	} else {
+		formatLogRecord(out, format, rec)
+	}
 	return w.Write(out.Bytes())
 }
 
-func formatLogRecord(out *bytes.Buffer, format string, rec *logRecord) {
+// 解析格式并缓存; 只用于LogWriter配置的格式, 外部传入的格式(如MemoryHandler的参数)应使用parseFormat
+func compileFormat(format string) []formatPiece {
+	if pieces, ok := formatCache.Load(format); ok {
+		return pieces.([]formatPiece)
+	}
+	pieces := parseFormat(format)
+	formatCache.Store(format, pieces)
+	return pieces
+}
+
+// 嵌入在有format的LogWriter中
+type logFormat struct {
+	format    string
+	goroutine bool // 格式含%G, 记录日志时需获取goroutine id
+}
+
+func (f *logFormat) setFormat(format string) {
+	f.format, f.goroutine = format, false
+	if format == JsonFormat {
+		return
+	}
+	for _, piece := range compileFormat(format) {
+		if piece.verb == 'G' {
+			f.goroutine = true
+		}
+	}
+}
+
+func (f *logFormat) needGoroutine() bool {
+	return f.goroutine
+}
+
+// 未知的占位符不输出, %%输出%, 末尾的%忽略
+func parseFormat(format string) []formatPiece {
+	pieces := make([]formatPiece, 0)
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			end := strings.IndexByte(format[i:], '%')
+			if end < 0 {
+				end = len(format) - i
+			}
+			pieces = append(pieces, formatPiece{text: format[i : i+end]})
+			i += end
+			continue
+		}
+
+		piece := formatPiece{}
+		i++
+		if i < len(format) && format[i] == '-' {
+			piece.left = true
+			i++
+		}
+		for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+			piece.width = piece.width*10 + int(format[i]-'0')
+		}
+		if i >= len(format) {
+			continue
+		}
+		if format[i] == '%' {
+			pieces = append(pieces, formatPiece{text: "%"})
+			i++
+			continue
+		}
+
+		piece.verb = format[i]
+		i++
+		switch piece.verb {
+		case 'D', 'F', 'X':
+			if i < len(format) && format[i] == '{' {
+				if end := strings.IndexByte(format[i:], '}'); end > 0 {
+					piece.text = format[i+1 : i+end]
+					i += end + 1
+				}
+			}
+		}
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+func formatLogRecord(out *bytes.Buffer, format string, rec *LogRecord) {
+	formatColorLogRecord(out, format, rec, "")
+}
+
+// levelColor不为空时, %L的输出以levelColor开始、以colorReset结束
+func formatColorLogRecord(out *bytes.Buffer, format string, rec *LogRecord, levelColor string) {
 	if rec == nil {
 		out.WriteString("\n")
 		return
@@ -29,51 +149,251 @@ func formatLogRecord(out *bytes.Buffer, format string, rec *logRecord) {
 	if len(format) == 0 {
 		return
 	}
+	formatPieces(out, compileFormat(format), rec, levelColor)
+}
+
+func formatPieces(out *bytes.Buffer, pieces []formatPiece, rec *LogRecord, levelColor string) {
+	for _, piece := range pieces {
+		if piece.verb == 0 {
+			out.WriteString(piece.text)
+			continue
+		}
+		colored := levelColor != "" && piece.verb == 'L'
+		if colored {
+			out.WriteString(levelColor)
+		}
+		start := out.Len()
+		writeVerb(out, piece, rec)
+		if piece.width > 0 {
+			padPiece(out, start, piece)
+		}
+		if colored {
+			out.WriteString(colorReset)
+		}
+	}
+	out.WriteByte('\n')
+}
+
+func writeVerb(out *bytes.Buffer, piece formatPiece, rec *LogRecord) {
+	switch piece.verb {
+	case 'T':
+		zone, _ := rec.Created.Zone()
+		out.WriteString(rec.Created.Format("15:04:05"))
+		out.WriteString(fmt.Sprintf(".%03d", rec.Created.UnixNano()/1e6%1000))
+		out.WriteByte(' ')
+		out.WriteString(zone)
+	case 't':
+		out.WriteString(rec.Created.Format("15:04"))
+	case 'D':
+		if piece.text != "" {
+			out.WriteString(rec.Created.Format(piece.text))
+		} else {
+			out.WriteString(rec.Created.Format("2006/01/02"))
+		}
+	case 'd':
+		out.WriteString(rec.Created.Format("01/02/"))
+		out.WriteString(rec.Created.Format("2006")[2:])
+	case 'L':
+		out.WriteString(levelStrings[rec.Level])
+	case 'S':
+		out.WriteString(rec.Source)
+	case 's':
+		sources := strings.Split(rec.Source, "/")
+		out.WriteString(sources[len(sources)-1])
+	case 'f':
+		sources := strings.Split(rec.Source, "/")
+		names := strings.Split(sources[len(sources)-1], ".")
+		out.WriteString(names[len(names)-1])
+	case 'F':
+		if piece.text == "long" {
+			out.WriteString(rec.File)
+		} else {
+			out.WriteString(filepath.Base(rec.File))
+		}
+	case 'M':
+		out.WriteString(rec.Message)
+		if rec.Stack != "" {
+			out.WriteByte('\n')
+			out.WriteString(rec.Stack)
+		}
+	case 'B':
+		out.WriteByte('\n')
+	case 'K':
+		writeFields(out, rec.Fields)
+	case 'X':
+		// %X{traceId}输出单个上下文值, %X输出全部
+		if piece.text != "" {
+			writeContextValue(out, rec.Context, piece.text)
+		} else {
+			writeFields(out, rec.Context)
+		}
+	case 'c':
+		out.WriteString(rec.Tag)
+	case 'P':
+		out.WriteString(strconv.Itoa(pid))
+	case 'H':
+		out.WriteString(hostname)
+	case 'G':
+		if rec.Goroutine > 0 {
+			out.WriteString(strconv.FormatInt(rec.Goroutine, 10))
+		}
+	}
+}
+
+// 占位符从start开始的输出不足width个字符时补空格
+func padPiece(out *bytes.Buffer, start int, piece formatPiece) {
+	n := utf8.RuneCount(out.Bytes()[start:])
+	if n >= piece.width {
+		return
+	}
+	pad := piece.width - n
+	for i := 0; i < pad; i++ {
+		out.WriteByte(' ')
+	}
+	if !piece.left {
+		bs := out.Bytes()
+		copy(bs[start+pad:], bs[start:len(bs)-pad])
+		for i := start; i < start+pad; i++ {
+			bs[i] = ' '
+		}
+	}
+}
 
-	// out := bytes.NewBuffer(make([]byte, 0, 64))
-
-	// Split the string into pieces by % signs
-	pieces := bytes.Split([]byte(format), sep)
-
-	// Iterate over the pieces, replacing known formats
-	for i, piece := range pieces {
-		if i > 0 && len(piece) > 0 {
-			switch piece[0] {
-			case 'T':
-				zone, _ := rec.Created.Zone()
-				out.WriteString(rec.Created.Format("15:04:05"))
-				out.WriteString(fmt.Sprintf(".%03d", rec.Created.UnixNano()/1e6%1000))
-				out.WriteByte(' ')
-				out.WriteString(zone)
-			case 't':
-				out.WriteString(rec.Created.Format("15:04"))
-			case 'D':
-				out.WriteString(rec.Created.Format("2006/01/02"))
-			case 'd':
-				out.WriteString(rec.Created.Format("01/02/"))
-				out.WriteString(rec.Created.Format("2006")[2:])
-			case 'L':
-				out.WriteString(levelStrings[rec.Level])
-			case 'S':
-				out.WriteString(rec.Source)
-			case 's':
-				sources := strings.Split(rec.Source, "/")
-				out.WriteString(sources[len(sources)-1])
-			case 'f':
-				sources := strings.Split(rec.Source, "/")
-				names := strings.Split(sources[len(sources)-1], ".")
-				out.WriteString(names[len(names)-1])
-			case 'M':
-				out.WriteString(rec.Message)
-			case 'B':
-				out.WriteByte('\n')
+func writeContextValue(out *bytes.Buffer, values []Field, key string) {
+	for _, value := range values {
+		if value.Key == key {
+			out.WriteString(fmt.Sprint(value.Value))
+			return
+		}
+	}
+}
+
+// 结构化字段以 k1=v1 k2=v2 输出; value含空格、引号、等号时加引号
+func writeFields(out *bytes.Buffer, fields []Field) {
+	for i, field := range fields {
+		if i > 0 {
+			out.WriteByte(' ')
+		}
+		out.WriteString(field.Key)
+		out.WriteByte('=')
+
+		value, ok := field.Value.(string)
+		if !ok {
+			value = fmt.Sprint(field.Value)
+		}
+		if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+			out.WriteString(strconv.Quote(value))
+		} else {
+			out.WriteString(value)
+		}
+	}
+}
+
+// {"time":"...","level":"INFO","source":"...","tag":"...","message":"...","stack":"...","fields":{...},"context":{...}}
+// tag、stack、fields、context为空时不输出
+func formatJsonLogRecord(out *bytes.Buffer, rec *LogRecord) {
+	out.WriteString(`{"time":`)
+	writeJsonString(out, rec.Created.Format("2006-01-02T15:04:05.000Z07:00"))
+	out.WriteString(`,"level":`)
+	writeJsonString(out, levelNames[rec.Level])
+	out.WriteString(`,"source":`)
+	writeJsonString(out, rec.Source)
+	if rec.Tag != "" {
+		out.WriteString(`,"tag":`)
+		writeJsonString(out, rec.Tag)
+	}
+	out.WriteString(`,"message":`)
+	writeJsonString(out, rec.Message)
+	if rec.Stack != "" {
+		out.WriteString(`,"stack":`)
+		writeJsonString(out, rec.Stack)
+	}
+	if len(rec.Fields) > 0 {
+		out.WriteString(`,"fields":{`)
+		for i, field := range rec.Fields {
+			if i > 0 {
+				out.WriteByte(',')
 			}
-			if len(piece) > 1 {
-				out.Write(piece[1:])
+			writeJsonString(out, field.Key)
+			out.WriteByte(':')
+			writeJsonValue(out, field.Value)
+		}
+		out.WriteByte('}')
+	}
+	if len(rec.Context) > 0 {
+		out.WriteString(`,"context":{`)
+		for i, value := range rec.Context {
+			if i > 0 {
+				out.WriteByte(',')
 			}
-		} else if len(piece) > 0 {
-			out.Write(piece)
+			writeJsonString(out, value.Key)
+			out.WriteByte(':')
+			writeJsonValue(out, value.Value)
 		}
+		out.WriteByte('}')
 	}
-	out.WriteByte('\n')
+	out.WriteString("}\n")
+}
+
+func writeJsonValue(out *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		writeJsonString(out, v)
+	case error:
+		writeJsonString(out, v.Error())
+	case fmt.Stringer:
+		writeJsonString(out, v.String())
+	default:
+		if bs, err := json.Marshal(v); err == nil {
+			out.Write(bs)
+		} else {
+			writeJsonString(out, fmt.Sprint(v)) // chan、func等无法序列化的类型
+		}
+	}
+}
+
+const jsonHex = "0123456789abcdef"
+
+// 转义双引号、反斜杠及控制字符, 非法utf8替换为\ufffd
+func writeJsonString(out *bytes.Buffer, s string) {
+	out.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			out.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				out.WriteByte('\\')
+				out.WriteByte(b)
+			case '\n':
+				out.WriteString(`\n`)
+			case '\r':
+				out.WriteString(`\r`)
+			case '\t':
+				out.WriteString(`\t`)
+			default:
+				out.WriteString(`\u00`)
+				out.WriteByte(jsonHex[b>>4])
+				out.WriteByte(jsonHex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			out.WriteString(s[start:i])
+			out.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	out.WriteString(s[start:])
+	out.WriteByte('"')
 }
@@ -0,0 +1,171 @@
+package log4j
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatJson(t *testing.T) {
+	rec := &LogRecord{
+		Level:   WARNING,
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC),
+		Source:  "main.run:12",
+		Tag:     "order",
+		Message: "quote\" backslash\\ newline\n tab\t ctrl\x01 bad\xff 中文",
+		Fields:  []Field{{"id", 42}, {"err", errors.New("boom")}, {"ch", make(chan int)}},
+		Context: []Field{{"traceId", "t-1"}},
+	}
+	out := &bytes.Buffer{}
+	formatJsonLogRecord(out, rec)
+
+	line := out.String()
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "}\n") {
+		t.Fatalf("expect one line, got %q", line)
+	}
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json %q: %s", line, err)
+	}
+
+	want := map[string]string{
+		"time":    "2024-01-02T03:04:05.006Z",
+		"level":   "WARNING",
+		"source":  "main.run:12",
+		"tag":     "order",
+		"message": "quote\" backslash\\ newline\n tab\t ctrl\x01 bad� 中文",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: got %#v, want %#v", key, got[key], value)
+		}
+	}
+	// 无法序列化的chan输出为fmt.Sprint的结果
+	fields, _ := got["fields"].(map[string]interface{})
+	if ch, _ := fields["ch"].(string); fields["id"] != 42.0 || fields["err"] != "boom" || !strings.HasPrefix(ch, "0x") {
+		t.Errorf("fields: %v", fields)
+	}
+	if context, _ := got["context"].(map[string]interface{}); context["traceId"] != "t-1" {
+		t.Errorf("context: %v", got["context"])
+	}
+}
+
+func TestFormatJsonOmitEmpty(t *testing.T) {
+	out := &bytes.Buffer{}
+	formatJsonLogRecord(out, &LogRecord{Level: INFO, Created: time.Now(), Message: "m"})
+	for _, key := range []string{`"tag"`, `"stack"`, `"fields"`, `"context"`} {
+		if strings.Contains(out.String(), key) {
+			t.Errorf("unexpected %s in %s", key, out.String())
+		}
+	}
+
+	// 空行在json格式下不输出
+	if n, err := fPrintFormatLog(out, JsonFormat, nil); n != 0 || err != nil {
+		t.Errorf("empty line: %d, %v", n, err)
+	}
+}
+
+func TestFormatJsonErrorStack(t *testing.T) {
+	w := &testLogWriter{}
+	logger := NewLogger()
+	logger.SetLogWriter("test", w)
+	logger.With("id", 1).ErrorStack("fail: %s", "disk \"full\"")
+
+	recs := w.records()
+	if len(recs) != 1 || recs[0].Stack == "" {
+		t.Fatalf("expect 1 record with stack, got %v", recs)
+	}
+
+	out := &bytes.Buffer{}
+	if _, err := fPrintFormatLog(out, JsonFormat, recs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("stack should be escaped into one line, got %q", out.String())
+	}
+	got := struct {
+		Message string
+		Stack   string
+		Fields  map[string]int
+	}{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json %q: %s", out.String(), err)
+	}
+	if got.Message != `fail: disk "full"` || got.Fields["id"] != 1 {
+		t.Errorf("got %+v", got)
+	}
+	if !strings.HasPrefix(got.Stack, "goroutine ") || !strings.Contains(got.Stack, "TestFormatJsonErrorStack") {
+		t.Errorf("stack: %q", got.Stack)
+	}
+}
+
+func TestFormatVerbs(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.FixedZone("CST", 8*3600))
+	rec := &LogRecord{
+		Level:     WARNING,
+		Created:   created,
+		Source:    "github.com/acme/order.(*Service).Create:12",
+		File:      "/src/acme/order/service.go:12",
+		Message:   "done",
+		Tag:       "order",
+		Goroutine: 42,
+		Fields:    []Field{{"id", 7}},
+		Context:   []Field{{"traceId", "t-1"}, {"user", "tom"}},
+	}
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"%T", "03:04:05.006 CST"},
+		{"%t", "03:04"},
+		{"%D", "2024/01/02"},
+		{"%d", "01/02/24"},
+		{"%D{2006-01-02T15:04:05.000Z07:00}", "2024-01-02T03:04:05.006+08:00"},
+		{"%D{15h04}", "03h04"},
+		{"%L", "WARN"},
+		{"%S", "github.com/acme/order.(*Service).Create:12"},
+		{"%s", "order.(*Service).Create:12"},
+		{"%f", "Create:12"},
+		{"%F", "service.go:12"},
+		{"%F{long}", "/src/acme/order/service.go:12"},
+		{"%M", "done"},
+		{"a%Bb", "a\nb"},
+		{"%K", "id=7"},
+		{"%X", "traceId=t-1 user=tom"},
+		{"%X{user}", "tom"},
+		{"%X{missing}", ""},
+		{"%c", "order"},
+		{"%P", strconv.Itoa(os.Getpid())},
+		{"%H", hostname},
+		{"%G", "42"},
+		{"100%% done", "100% done"},
+		{"%%L", "%L"},
+		{"[%6L]", "[  WARN]"},
+		{"[%-6L]", "[WARN  ]"},
+		{"[%2L]", "[WARN]"},
+		{"[%-8c]", "[order   ]"},
+		{"[%4M]", "[done]"},
+		{"%Q%M", "done"}, // 未知的占位符不输出
+		{"end%", "end"},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		formatLogRecord(out, c.format, rec)
+		want := c.want + "\n"
+		if out.String() != want {
+			t.Errorf("%q: got %q, want %q", c.format, out.String(), want)
+		}
+	}
+
+	// 没有goroutine id时%G不输出
+	out := &bytes.Buffer{}
+	formatLogRecord(out, "[%G]", &LogRecord{})
+	if out.String() != "[]\n" {
+		t.Errorf("%%G without goroutine: %q", out.String())
+	}
+}
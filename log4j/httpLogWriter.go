@@ -0,0 +1,290 @@
+package log4j
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	httpMinBackoff = time.Millisecond * 500
+
+	defaultHttpBatchSize    = 100
+	defaultHttpBatchLatency = time.Second
+	defaultHttpQueueSize    = 1000
+	defaultHttpMaxRetries   = 3
+	defaultHttpMaxBackoff   = time.Second * 10
+	defaultHttpTimeout      = time.Second * 10
+)
+
+// 攒批后以ndjson(每行一条json日志)POST到http接口; 达到batchSize条或最早一条等待超过batchLatency时发送
+type HttpLogWriter struct {
+	*logChannel
+
+	level   Level
+	tag     string
+	closeCh chan bool
+	private bool
+
+	url          string
+	header       http.Header
+	gzip         bool
+	batchSize    int
+	batchLatency time.Duration
+	maxRetries   int
+	maxBackoff   time.Duration
+	client       *http.Client
+
+	failed      int64 // 发送失败(重试后仍失败)而丢弃的日志数
+	notNotified int64 // 尚未输出提示的发送失败数
+}
+
+// queueSize为待发送日志的缓冲区大小, 满时按OverflowPolicy处理, 默认OverflowDropOldest:
+// 接口不可用时重试会阻塞发送, 丢弃最旧的日志以免阻塞写日志的goroutine
+func NewHttpLogWriter(tag string, level Level, url string, queueSize int) *HttpLogWriter {
+	if queueSize <= 0 {
+		queueSize = defaultHttpQueueSize
+	}
+	writer := &HttpLogWriter{
+		level:        level,
+		tag:          tag,
+		logChannel:   newLogChannel(queueSize),
+		closeCh:      make(chan bool),
+		url:          url,
+		header:       http.Header{},
+		batchSize:    defaultHttpBatchSize,
+		batchLatency: defaultHttpBatchLatency,
+		maxRetries:   defaultHttpMaxRetries,
+		maxBackoff:   defaultHttpMaxBackoff,
+		client:       &http.Client{Timeout: defaultHttpTimeout},
+	}
+	writer.SetOverflow(OverflowDropOldest, 0)
+	go writer.run()
+
+	printlnIO(os.Stdout, "INFO", "httpLogWriter[%s], create success, url:%s", tag, url)
+	return writer
+}
+
+func (w *HttpLogWriter) run() {
+	defer func() {
+		w.closeCh <- true
+	}()
+
+	batch := []*LogRecord(nil)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		select {
+		case rec, isAlive := <-w.logChannel.ch:
+			if !isAlive {
+				w.post(batch) // Close时发送剩余的日志
+				timer.Stop()
+				return
+			}
+			if rec == nil {
+				continue // 空行对json无意义
+			}
+
+			if len(batch) == 0 {
+				timer.Reset(w.batchLatency)
+			}
+			batch = append(batch, rec)
+			if notice := w.logChannel.droppedNotice(); notice != nil {
+				batch = append(batch, notice)
+			}
+			if notice := w.failedNotice(); notice != nil {
+				batch = append(batch, notice)
+			}
+
+			if len(batch) >= w.batchSize {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				w.post(batch)
+				batch = batch[:0]
+			}
+
+		case <-timer.C:
+			w.post(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// 网络错误、429及5xx时按退避间隔重试, 超过maxRetries后丢弃该批日志
+func (w *HttpLogWriter) post(batch []*LogRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := w.encode(batch)
+	if err != nil {
+		printlnIO(os.Stderr, "ERROR", "httpLogWriter[%s] encode %d records fail, err:%s", w.tag, len(batch), err.Error())
+		return
+	}
+
+	backoff := httpMinBackoff
+	for retry := 0; ; retry++ {
+		retryable := false
+		if retryable, err = w.doPost(body); err == nil {
+			return
+		}
+		if !retryable || retry >= w.maxRetries {
+			break
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+
+	atomic.AddInt64(&w.failed, int64(len(batch)))
+	atomic.AddInt64(&w.notNotified, int64(len(batch)))
+	printlnIO(os.Stderr, "ERROR", "httpLogWriter[%s] post %d records to %s fail, err:%s", w.tag, len(batch), w.url, err.Error())
+}
+
+// 有未提示的发送失败时, 返回一条提示日志, 随下一批日志发送
+func (w *HttpLogWriter) failedNotice() *LogRecord {
+	n := atomic.SwapInt64(&w.notNotified, 0)
+	if n == 0 {
+		return nil
+	}
+	return &LogRecord{
+		Level:   WARNING,
+		Created: time.Now(),
+		Source:  "log4j",
+		Message: fmt.Sprintf("%d log records lost, post to %s failed", n, w.url),
+	}
+}
+
+// 发送失败(重试后仍失败)而丢弃的日志数; 因缓冲区满而丢弃的见GetDropped
+func (w *HttpLogWriter) GetFailed() int64 {
+	return atomic.LoadInt64(&w.failed)
+}
+
+func (w *HttpLogWriter) encode(batch []*LogRecord) ([]byte, error) {
+	body := &bytes.Buffer{}
+	out := io.Writer(body)
+
+	zw := (*gzip.Writer)(nil)
+	if w.gzip {
+		zw = gzip.NewWriter(body)
+		out = zw
+	}
+
+	line := &bytes.Buffer{}
+	for _, rec := range batch {
+		line.Reset()
+		formatJsonLogRecord(line, rec)
+		if _, err := out.Write(line.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return body.Bytes(), nil
+}
+
+func (w *HttpLogWriter) doPost(body []byte) (retryable bool, err error) {
+	request, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range w.header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	if w.gzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	_ = response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retryable, fmt.Errorf("response status: %s", response.Status)
+}
+
+func (w *HttpLogWriter) LogWrite(rec *LogRecord) {
+	w.logChannel.put(rec)
+}
+
+// 发送完剩余日志(包括重试)后返回
+func (w *HttpLogWriter) Close() {
+	w.logChannel.close()
+	<-w.closeCh
+	printlnIO(os.Stdout, "INFO", "httpLogWriter[%s] is closed", w.tag)
+}
+
+func (w *HttpLogWriter) IsPrivate() bool {
+	return w.private
+}
+
+func (w *HttpLogWriter) GetLevel() Level {
+	return w.level
+}
+
+func (w *HttpLogWriter) SetPrivate(private bool) *HttpLogWriter {
+	w.private = private
+	return w
+}
+
+// 每个请求都携带的header, 如Authorization
+func (w *HttpLogWriter) SetHeader(name, value string) *HttpLogWriter {
+	w.header.Add(name, value)
+	return w
+}
+
+func (w *HttpLogWriter) SetGzip(gzip bool) *HttpLogWriter {
+	w.gzip = gzip
+	return w
+}
+
+func (w *HttpLogWriter) SetBatch(batchSize int, batchLatency time.Duration) *HttpLogWriter {
+	if batchSize > 0 {
+		w.batchSize = batchSize
+	}
+	if batchLatency > 0 {
+		w.batchLatency = batchLatency
+	}
+	return w
+}
+
+// 每批日志发送失败后最多重试maxRetries次, 退避间隔从500ms开始翻倍, 不超过maxBackoff
+func (w *HttpLogWriter) SetRetry(maxRetries int, maxBackoff time.Duration) *HttpLogWriter {
+	if maxRetries >= 0 {
+		w.maxRetries = maxRetries
+	}
+	if maxBackoff > 0 {
+		w.maxBackoff = maxBackoff
+	}
+	return w
+}
+
+func (w *HttpLogWriter) SetTimeout(timeout time.Duration) *HttpLogWriter {
+	if timeout > 0 {
+		w.client.Timeout = timeout
+	}
+	return w
+}
@@ -0,0 +1,218 @@
+package log4j
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录收到的请求, 按statuses依次返回状态码, 用完后返回200
+type testCollector struct {
+	lock     sync.Mutex
+	statuses []int
+	requests []*testRequest
+	received chan bool
+}
+
+type testRequest struct {
+	header   http.Header
+	messages []string
+}
+
+func newTestCollector(t *testing.T, statuses ...int) (*testCollector, *httptest.Server) {
+	c := &testCollector{statuses: statuses, received: make(chan bool, 100)}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body := io.Reader(request.Body)
+		if request.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(request.Body)
+			if err != nil {
+				t.Errorf("gzip.NewReader err: %s", err)
+				return
+			}
+			body = zr
+		}
+		bs, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Errorf("read body err: %s", err)
+		}
+
+		req := &testRequest{header: request.Header}
+		scanner := bufio.NewScanner(bytes.NewReader(bs))
+		for scanner.Scan() {
+			line := map[string]interface{}{}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Errorf("invalid ndjson line %q: %s", scanner.Text(), err)
+			}
+			message, _ := line["message"].(string)
+			req.messages = append(req.messages, message)
+		}
+
+		c.lock.Lock()
+		c.requests = append(c.requests, req)
+		status := http.StatusOK
+		if len(c.statuses) > 0 {
+			status, c.statuses = c.statuses[0], c.statuses[1:]
+		}
+		c.lock.Unlock()
+
+		writer.WriteHeader(status)
+		c.received <- true
+	}))
+	return c, server
+}
+
+func (c *testCollector) wait(t *testing.T, n int) []*testRequest {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-c.received:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for request %d", i+1)
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*testRequest(nil), c.requests...)
+}
+
+func testRecord(message string) *LogRecord {
+	return &LogRecord{Level: INFO, Created: time.Now(), Source: "test", Message: message}
+}
+
+func TestHttpBatchBySize(t *testing.T) {
+	collector, server := newTestCollector(t)
+	defer server.Close()
+
+	w := NewHttpLogWriter("http", INFO, server.URL, 0).SetBatch(3, time.Hour)
+	defer w.Close()
+	for _, message := range []string{"a", "b", "c", "d", "e", "f"} {
+		w.LogWrite(testRecord(message))
+	}
+
+	requests := collector.wait(t, 2)
+	if len(requests[0].messages) != 3 || len(requests[1].messages) != 3 {
+		t.Fatalf("expect 2 batches of 3, got %v, %v", requests[0].messages, requests[1].messages)
+	}
+	if requests[0].header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Content-Type: %s", requests[0].header.Get("Content-Type"))
+	}
+}
+
+func TestHttpBatchByLatency(t *testing.T) {
+	collector, server := newTestCollector(t)
+	defer server.Close()
+
+	w := NewHttpLogWriter("http", INFO, server.URL, 0).SetBatch(100, 50*time.Millisecond)
+	defer w.Close()
+	start := time.Now()
+	w.LogWrite(testRecord("a"))
+	w.LogWrite(testRecord("b"))
+
+	requests := collector.wait(t, 1)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("sent after %s, before batchLatency", elapsed)
+	}
+	if len(requests[0].messages) != 2 {
+		t.Errorf("expect 1 batch of 2, got %v", requests[0].messages)
+	}
+}
+
+func TestHttpGzipAndHeader(t *testing.T) {
+	collector, server := newTestCollector(t)
+	defer server.Close()
+
+	w := NewHttpLogWriter("http", INFO, server.URL, 0).SetGzip(true).SetHeader("Authorization", "Bearer token")
+	w.LogWrite(testRecord("zipped"))
+	w.Close() // Close时发送剩余的日志
+
+	requests := collector.wait(t, 1)
+	if requests[0].header.Get("Content-Encoding") != "gzip" || requests[0].header.Get("Authorization") != "Bearer token" {
+		t.Errorf("unexpected header: %v", requests[0].header)
+	}
+	if len(requests[0].messages) != 1 || requests[0].messages[0] != "zipped" {
+		t.Errorf("got %v", requests[0].messages)
+	}
+}
+
+func TestHttpRetry(t *testing.T) {
+	collector, server := newTestCollector(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+
+	w := NewHttpLogWriter("http", INFO, server.URL, 0).SetRetry(3, time.Millisecond)
+	w.LogWrite(testRecord("retried"))
+	w.Close()
+
+	requests := collector.wait(t, 3)
+	for i, request := range requests {
+		if len(request.messages) != 1 || request.messages[0] != "retried" {
+			t.Errorf("request %d: got %v", i, request.messages)
+		}
+	}
+	if w.GetFailed() != 0 {
+		t.Errorf("GetFailed() = %d", w.GetFailed())
+	}
+}
+
+func TestHttpDropOnFailure(t *testing.T) {
+	cases := []struct {
+		desc     string
+		statuses []int
+		requests int // 第一批日志的请求次数
+	}{
+		{"400 not retried", []int{http.StatusBadRequest}, 1},
+		{"500 retried then dropped", []int{http.StatusInternalServerError, http.StatusInternalServerError}, 2},
+	}
+	for _, c := range cases {
+		collector, server := newTestCollector(t, c.statuses...)
+
+		w := NewHttpLogWriter("http", INFO, server.URL, 0).SetBatch(2, time.Hour).SetRetry(1, time.Millisecond)
+		w.LogWrite(testRecord("a"))
+		w.LogWrite(testRecord("b"))
+		collector.wait(t, c.requests)
+
+		w.LogWrite(testRecord("c"))
+		w.Close()
+		requests := collector.wait(t, 1)
+		server.Close()
+
+		if len(requests) != c.requests+1 {
+			t.Errorf("%s: %d requests, want %d", c.desc, len(requests), c.requests+1)
+		}
+		if got := w.GetFailed(); got != 2 {
+			t.Errorf("%s: GetFailed() = %d, want 2", c.desc, got)
+		}
+		if got := w.GetDropped(); got != 0 {
+			t.Errorf("%s: GetDropped() = %d, want 0", c.desc, got)
+		}
+		// 发送失败的提示随下一批日志发送
+		last := requests[len(requests)-1].messages
+		if len(last) != 2 || last[0] != "c" || last[1] != "2 log records lost, post to "+server.URL+" failed" {
+			t.Errorf("%s: last batch %q", c.desc, last)
+		}
+	}
+}
+
+func TestHttpDefaultOverflow(t *testing.T) {
+	w := NewHttpLogWriter("http", INFO, "http://127.0.0.1:0", 0)
+	defer w.Close()
+	if w.policy != OverflowDropOldest {
+		t.Errorf("default policy %s, want %s", w.policy, OverflowDropOldest)
+	}
+
+	c, err := parseConfig([]byte(`<logging><filter enabled="true"><tag>http</tag><type>http</type><level>INFO</level>` +
+		`<property name="url">http://127.0.0.1/logs</property></filter></logging>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy, _ := c.filters[0].prop.overflowPolicy(); policy != OverflowDropOldest {
+		t.Errorf("configured default policy %s, want %s", policy, OverflowDropOldest)
+	}
+}
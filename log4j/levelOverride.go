@@ -0,0 +1,160 @@
+package log4j
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 运行时修改的LogWriter级别, 优先于配置中的级别, 且该LogWriter不受<logger>级别限制; 重新加载配置后, 新配置中仍有该tag时保留
+type levelOverride struct {
+	level  Level
+	expire time.Time // 为零时不过期
+}
+
+func (o *levelOverride) expired(now time.Time) bool {
+	return !o.expire.IsZero() && !now.Before(o.expire)
+}
+
+// 一个LogWriter的级别信息
+type LevelInfo struct {
+	Tag        string     `json:"tag"`
+	Level      string     `json:"level"`            // 当前生效的级别
+	Configured string     `json:"configured"`       // 配置中的级别
+	Expire     *time.Time `json:"expire,omitempty"` // 运行时修改的级别到期后恢复为Configured
+}
+
+// 修改tag对应LogWriter的级别, 直到ResetLevel
+func (p *Logger) SetLevel(tag string, lvl Level) error {
+	return p.SetLevelFor(tag, lvl, 0)
+}
+
+// 修改tag对应LogWriter的级别, duration后自动恢复; duration<=0时不自动恢复
+func (p *Logger) SetLevelFor(tag string, lvl Level, duration time.Duration) error {
+	if lvl < 0 || int(lvl) >= len(levelNames) {
+		return fmt.Errorf("unsupported level: %d", lvl)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.logWriterMap[tag]; !ok {
+		return fmt.Errorf("log writer not found, tag: %s", tag)
+	}
+
+	override := &levelOverride{level: lvl}
+	if duration > 0 {
+		override.expire = time.Now().Add(duration)
+	}
+	if p.levelOverrides == nil {
+		p.levelOverrides = map[string]*levelOverride{}
+	}
+	p.levelOverrides[tag] = override
+	return nil
+}
+
+// 恢复为配置中的级别
+func (p *Logger) ResetLevel(tag string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.levelOverrides, tag)
+}
+
+// 各LogWriter当前生效的级别
+func (p *Logger) GetLevels() map[string]Level {
+	levels := map[string]Level{}
+	for _, info := range p.levelInfos() {
+		levels[info.Tag], _ = parseLevel(info.Level)
+	}
+	return levels
+}
+
+func (p *Logger) levelInfos() []*LevelInfo {
+	now := time.Now()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	infos := make([]*LevelInfo, 0, len(p.logWriterMap))
+	for tag, logWriter := range p.logWriterMap {
+		info := &LevelInfo{Tag: tag, Level: levelNames[logWriter.GetLevel()], Configured: levelNames[logWriter.GetLevel()]}
+		if override, ok := p.levelOverrides[tag]; ok {
+			if override.expired(now) {
+				delete(p.levelOverrides, tag)
+			} else {
+				info.Level = levelNames[override.level]
+				if !override.expire.IsZero() {
+					expire := override.expire
+					info.Expire = &expire
+				}
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Tag < infos[j].Tag
+	})
+	return infos
+}
+
+// 持有读锁时调用; 返回tag对应的未过期的运行时级别
+func (p *Logger) overrideLevel(tag string, now time.Time) (Level, bool) {
+	if len(p.levelOverrides) == 0 {
+		return 0, false
+	}
+	if override, ok := p.levelOverrides[tag]; ok && !override.expired(now) {
+		return override.level, true
+	}
+	return 0, false
+}
+
+// GET返回各LogWriter的级别(json); POST修改级别, 参数: tag, level(为空或reset时恢复配置中的级别),
+// duration(如10m, 到期后恢复, 可选)
+func (p *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			if err := p.setLevelByRequest(request); err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(p.levelInfos())
+	})
+}
+
+func (p *Logger) setLevelByRequest(request *http.Request) error {
+	tag, value := request.FormValue("tag"), strings.ToUpper(request.FormValue("level"))
+	if tag == "" {
+		return fmt.Errorf("missing param: tag")
+	}
+
+	if value == "" || value == "RESET" {
+		p.ResetLevel(tag)
+		return nil
+	}
+
+	lvl, ok := parseLevel(value)
+	if !ok {
+		return fmt.Errorf("unsupported level: %s", request.FormValue("level"))
+	}
+
+	duration := time.Duration(0)
+	if value := request.FormValue("duration"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration: %s", value)
+		}
+		duration = d
+	}
+	return p.SetLevelFor(tag, lvl, duration)
+}
@@ -0,0 +1,98 @@
+package log4j
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 运行时修改过级别的LogWriter不受<logger>限制, 到期或ResetLevel后恢复
+func TestLevelOverrideBypassesRules(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+	config := strings.Replace(memoryConfig("a", "INFO", "b", "INFO"), "</logging>", `<logger name="root" level="WARNING"/></logging>`, 1)
+	if err := logger.LoadConfigurationFromReader(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("1 gated")
+	if err := logger.SetLevelFor("a", DEBUG, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetLevel("b", ERROR); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("2 debug")
+	logger.Warn("3 warn")
+	logger.Error("4 error")
+
+	time.Sleep(60 * time.Millisecond)
+	logger.ResetLevel("b")
+	logger.Info("5 gated again")
+	logger.Warn("6 warn")
+
+	if got := memoryMessages(logger, "a"); strings.Join(got, ",") != "2 debug,3 warn,4 error,6 warn" {
+		t.Errorf("a: %v", got)
+	}
+	if got := memoryMessages(logger, "b"); strings.Join(got, ",") != "4 error,6 warn" {
+		t.Errorf("b: %v", got)
+	}
+	if levels := logger.GetLevels(); levels["a"] != INFO || levels["b"] != INFO {
+		t.Errorf("levels after expiry and reset: %v", levels)
+	}
+
+	if err := logger.SetLevel("missing", DEBUG); err == nil {
+		t.Error("expect error for unknown tag")
+	}
+	if err := logger.SetLevel("a", Level(len(levelNames))); err == nil {
+		t.Error("expect error for unsupported level")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+	if err := logger.LoadConfigurationFromReader(strings.NewReader(memoryConfig("a", "INFO"))); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method string, params url.Values) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/debug/level", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		logger.LevelHandler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := request(http.MethodPost, url.Values{"tag": {"a"}, "level": {"debug"}, "duration": {"1m"}})
+	infos := make([]LevelInfo, 0)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &infos); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("code %d, body %s", recorder.Code, recorder.Body.String())
+	}
+	if len(infos) != 1 || infos[0].Level != "DEBUG" || infos[0].Configured != "INFO" || infos[0].Expire == nil {
+		t.Errorf("infos: %+v", infos)
+	}
+
+	request(http.MethodPost, url.Values{"tag": {"a"}, "level": {"reset"}})
+	if levels := logger.GetLevels(); levels["a"] != INFO {
+		t.Errorf("after reset: %v", levels)
+	}
+
+	for _, params := range []url.Values{
+		{"level": {"DEBUG"}},
+		{"tag": {"a"}, "level": {"LOUD"}},
+		{"tag": {"a"}, "level": {"DEBUG"}, "duration": {"-1m"}},
+		{"tag": {"missing"}, "level": {"DEBUG"}},
+	} {
+		if recorder := request(http.MethodPost, params); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%v: code %d, want 400", params, recorder.Code)
+		}
+	}
+	if recorder := request(http.MethodDelete, nil); recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: code %d", recorder.Code)
+	}
+}
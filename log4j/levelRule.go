@@ -0,0 +1,94 @@
+package log4j
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// <logger name="root">匹配所有未匹配其他<logger>的调用方
+const rootLoggerName = "root"
+
+// 按调用方的包/函数路径设置的级别, 如<logger name="github.com/acme/order" level="DEBUG"/>;
+// 低于该级别的日志不输出, 不低于的再按各LogWriter的级别判断.
+// 如LogWriter为DEBUG、root为INFO、个别包为DEBUG时, 只有这些包输出DEBUG日志, 级别为ERROR的LogWriter仍只输出ERROR
+type levelRule struct {
+	name  string
+	level Level
+}
+
+// 按最长前缀匹配LogRecord.Source中的函数名, 结果按函数名缓存
+type levelRules struct {
+	rules []levelRule // 按name长度倒序, 第一个匹配的即最长前缀
+	root  *levelRule  // 都不匹配时使用
+	cache sync.Map    // 函数名 -> *levelRule, 未匹配时为nil
+}
+
+func newLevelRules(rules []levelRule) *levelRules {
+	if len(rules) == 0 {
+		return nil
+	}
+	r := &levelRules{}
+	for i := range rules {
+		if rules[i].name == rootLoggerName {
+			root := rules[i]
+			r.root = &root
+		} else {
+			r.rules = append(r.rules, rules[i])
+		}
+	}
+	sort.SliceStable(r.rules, func(i, j int) bool {
+		return len(r.rules[i].name) > len(r.rules[j].name)
+	})
+	return r
+}
+
+// 返回source匹配的级别; 未配置或不匹配时ok为false
+func (r *levelRules) match(source string) (lvl Level, ok bool) {
+	if r == nil || source == "" {
+		return 0, false
+	}
+
+	funcName := sourceFuncName(source)
+
+	if cached, found := r.cache.Load(funcName); found {
+		if rule := cached.(*levelRule); rule != nil {
+			return rule.level, true
+		}
+		return 0, false
+	}
+
+	matched := r.root
+	for i := range r.rules {
+		if isPathPrefix(funcName, r.rules[i].name) {
+			matched = &r.rules[i]
+			break
+		}
+	}
+	r.cache.Store(funcName, matched)
+
+	if matched == nil {
+		return 0, false
+	}
+	return matched.level, true
+}
+
+// source为 函数名:行号
+func sourceFuncName(source string) string {
+	if i := strings.LastIndexByte(source, ':'); i > 0 {
+		return source[:i]
+	}
+	return source
+}
+
+// prefix后须为结尾、'.'或'/', 避免github.com/acme/order匹配github.com/acme/orderx
+func isPathPrefix(funcName, prefix string) bool {
+	if !strings.HasPrefix(funcName, prefix) {
+		return false
+	}
+	if len(funcName) == len(prefix) || strings.HasSuffix(prefix, "/") || strings.HasSuffix(prefix, ".") {
+		return true
+	}
+	next := funcName[len(prefix)]
+	return next == '.' || next == '/'
+}
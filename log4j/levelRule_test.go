@@ -0,0 +1,97 @@
+package log4j
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLevelRulesMatch(t *testing.T) {
+	rules := newLevelRules([]levelRule{
+		{"root", WARNING},
+		{"github.com/acme", INFO},
+		{"github.com/acme/order", DEBUG},
+		{"github.com/acme/order.(*Service).Pay", ERROR},
+		{"github.com/acme/pay/", TRACE},
+	})
+
+	cases := []struct {
+		source string
+		level  Level
+		ok     bool
+	}{
+		{"github.com/acme/order.Create:12", DEBUG, true},
+		{"github.com/acme/order/db.Query:3", DEBUG, true},
+		{"github.com/acme/order.(*Service).Pay:30", ERROR, true},    // 最长前缀
+		{"github.com/acme/order.(*Service).PayAll:31", DEBUG, true}, // 函数名也按边界匹配
+		{"github.com/acme/orderx.Create:5", INFO, true},             // 路径边界, 不匹配github.com/acme/order
+		{"github.com/acme/pay/api.Do:8", TRACE, true},               // 以'/'结尾的name直接按前缀匹配
+		{"github.com/acme.Init:1", INFO, true},
+		{"github.com/other.Run:1", WARNING, true}, // root
+		{"main.main", WARNING, true},              // 无行号
+		{"", 0, false},
+	}
+	for _, c := range cases {
+		// 第二次从缓存中取得, 结果应一致
+		for i := 0; i < 2; i++ {
+			if level, ok := rules.match(c.source); level != c.level || ok != c.ok {
+				t.Errorf("match(%s) #%d = %s, %v, want %s, %v", c.source, i, level, ok, c.level, c.ok)
+			}
+		}
+	}
+
+	// 缓存按函数名, 不同行号共用一项
+	rules.match("github.com/acme/order.Create:99")
+	cached, found := rules.cache.Load("github.com/acme/order.Create")
+	if rule, _ := cached.(*levelRule); !found || rule == nil || rule.name != "github.com/acme/order" {
+		t.Errorf("cache entry %v, %v", cached, found)
+	}
+
+	// 没有root时不匹配的调用方不受限制, 并缓存nil
+	noRoot := newLevelRules([]levelRule{{"github.com/acme", INFO}})
+	if _, ok := noRoot.match("main.main:1"); ok {
+		t.Error("expect no match without root")
+	}
+	if cached, found := noRoot.cache.Load("main.main"); !found || cached.(*levelRule) != nil {
+		t.Errorf("expect cached nil, got %v, %v", cached, found)
+	}
+
+	if newLevelRules(nil) != nil {
+		t.Error("expect nil rules without <logger>")
+	}
+	if _, ok := (*levelRules)(nil).match("main.main:1"); ok {
+		t.Error("nil rules should not match")
+	}
+}
+
+// <logger>只过滤, 不降低LogWriter的级别
+func TestLevelRulesGate(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+	err := logger.LoadConfigurationFromReader(strings.NewReader(`<logging>
+<filter enabled="true"><tag>debug</tag><type>memory</type><level>DEBUG</level></filter>
+<filter enabled="true"><tag>error</tag><type>memory</type><level>ERROR</level></filter>
+<logger name="root" level="WARNING"/>
+<logger name="github.com/ZhouJunjun/goLib/log4j.TestLevelRulesGate" level="DEBUG"/>
+</logging>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("debug")
+	logFunc := func() { logger.Debug("func debug") } // TestLevelRulesGate.func1, 仍在路径边界内
+	logFunc()
+	logLevelRuleOther(logger) // 匹配root
+	logger.Error("error")
+
+	if got := memoryMessages(logger, "debug"); strings.Join(got, ",") != "debug,func debug,other warn,error" {
+		t.Errorf("debug writer: %v", got)
+	}
+	if got := memoryMessages(logger, "error"); strings.Join(got, ",") != "error" {
+		t.Errorf("error writer should keep its own level: %v", got)
+	}
+}
+
+func logLevelRuleOther(logger *Logger) {
+	logger.Info("other info")
+	logger.Warn("other warn")
+}
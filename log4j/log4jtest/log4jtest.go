@@ -0,0 +1,141 @@
+// 测试中捕获log4j输出的日志, 并提供断言:
+//
+//	logs := log4jtest.Capture(t)
+//	doSomething()
+//	logs.ExpectError("connect fail")
+package log4jtest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ZhouJunjun/goLib/log4j"
+)
+
+// 捕获期间的全部日志, 同时是一个非私有、接收所有级别的LogWriter
+type Recorder struct {
+	t       testing.TB
+	lock    sync.Mutex
+	records []*log4j.LogRecord
+}
+
+// 在测试期间用Recorder替换默认Logger的全部LogWriter, 测试结束时换回原来的.
+// 捕获期间<logger>级别规则及路由规则不生效, 各级别、各tag的日志都会被捕获.
+// 默认Logger是全局的, 使用t.Parallel()的测试应通过CaptureLogger捕获各自的Logger
+func Capture(t testing.TB) *Recorder {
+	return CaptureLogger(t, log4j.Default())
+}
+
+func CaptureLogger(t testing.TB, logger *log4j.Logger) *Recorder {
+	recorder := &Recorder{t: t}
+	t.Cleanup(logger.Intercept("log4jtest", recorder))
+	return recorder
+}
+
+func (r *Recorder) LogWrite(rec *log4j.LogRecord) {
+	if rec == nil {
+		return // 空行
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = append(r.records, rec)
+}
+
+func (r *Recorder) Close() {
+}
+
+func (r *Recorder) IsPrivate() bool {
+	return false
+}
+
+func (r *Recorder) GetLevel() log4j.Level {
+	return log4j.TRACE
+}
+
+// 已捕获的全部日志, 按写入顺序
+func (r *Recorder) Records() []*log4j.LogRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]*log4j.LogRecord(nil), r.records...)
+}
+
+// 级别为lvl且Message包含contains的日志; contains为空时不限制
+func (r *Recorder) Find(lvl log4j.Level, contains string) []*log4j.LogRecord {
+	found := make([]*log4j.LogRecord, 0)
+	for _, rec := range r.Records() {
+		if rec.Level == lvl && strings.Contains(rec.Message, contains) {
+			found = append(found, rec)
+		}
+	}
+	return found
+}
+
+// 以tag输出(如InfoTag、LogTag)且Message包含contains的日志
+func (r *Recorder) FindTag(tag string, contains string) []*log4j.LogRecord {
+	found := make([]*log4j.LogRecord, 0)
+	for _, rec := range r.Records() {
+		if rec.Tag == tag && strings.Contains(rec.Message, contains) {
+			found = append(found, rec)
+		}
+	}
+	return found
+}
+
+// 清空已捕获的日志
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = nil
+}
+
+func (r *Recorder) Expect(lvl log4j.Level, contains string) {
+	r.t.Helper()
+	if len(r.Find(lvl, contains)) == 0 {
+		r.t.Errorf("log4jtest: expect %s log containing %q, got:\n%s", lvl, contains, r.dump())
+	}
+}
+
+func (r *Recorder) ExpectError(contains string) {
+	r.t.Helper()
+	r.Expect(log4j.ERROR, contains)
+}
+
+func (r *Recorder) ExpectTag(tag string, contains string) {
+	r.t.Helper()
+	if len(r.FindTag(tag, contains)) == 0 {
+		r.t.Errorf("log4jtest: expect log of tag %s containing %q, got:\n%s", tag, contains, r.dump())
+	}
+}
+
+// 不应有ERROR及以上级别的日志
+func (r *Recorder) ExpectNoError() {
+	r.t.Helper()
+	r.ExpectNone(log4j.ERROR)
+}
+
+// 不应有lvl及以上级别的日志
+func (r *Recorder) ExpectNone(lvl log4j.Level) {
+	r.t.Helper()
+	for _, rec := range r.Records() {
+		if rec.Level >= lvl {
+			r.t.Errorf("log4jtest: expect no log at or above %s, got:\n%s", lvl, r.dump())
+			return
+		}
+	}
+}
+
+func (r *Recorder) dump() string {
+	lines := make([]string, 0)
+	for _, rec := range r.Records() {
+		line := "[" + rec.Level.String() + "] (" + rec.Source + ") " + rec.Message
+		if rec.Tag != "" {
+			line = "[" + rec.Tag + "] " + line
+		}
+		lines = append(lines, "\t"+line)
+	}
+	if len(lines) == 0 {
+		return "\t(no log)"
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,124 @@
+package log4jtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ZhouJunjun/goLib/log4j"
+)
+
+// 记录断言失败而不使测试失败, 用于检查Expect*的结果
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+const testConfig = `<logging>
+<filter enabled="true"><tag>mem</tag><type>memory</type><level>ERROR</level></filter>
+<filter enabled="true"><tag>audit</tag><type>memory</type><level>INFO</level><property name="private">true</property></filter>
+<logger name="root" level="ERROR"/>
+</logging>`
+
+func memoryMessages(memory *log4j.MemoryLogWriter) []string {
+	messages := make([]string, 0)
+	for _, rec := range memory.Query(log4j.MemoryQuery{}) {
+		messages = append(messages, rec.Message)
+	}
+	return messages
+}
+
+// 捕获期间<logger>级别规则及私有LogWriter不生效, 测试结束后恢复
+func TestCaptureLogger(t *testing.T) {
+	logger := log4j.NewLogger()
+	defer logger.Close()
+	if err := logger.LoadConfigurationFromReader(strings.NewReader(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	mem, audit := logger.MemoryLogWriters()["mem"], logger.MemoryLogWriters()["audit"]
+
+	t.Run("capture", func(t *testing.T) {
+		logs := CaptureLogger(t, logger)
+		logger.Debug("debug below root")
+		logger.InfoTag("audit", "audit event")
+		logger.Error("error %d", 1)
+
+		if got := len(logs.Records()); got != 3 {
+			t.Fatalf("captured %d records", got)
+		}
+		logs.Expect(log4j.DEBUG, "below root")
+		logs.ExpectTag("audit", "event")
+		logs.ExpectError("error 1")
+		if len(memoryMessages(mem)) != 0 || len(memoryMessages(audit)) != 0 {
+			t.Error("log written to replaced writer")
+		}
+	})
+
+	logger.Debug("debug after")
+	logger.ErrorTag("audit", "audit after")
+	logger.Error("error after")
+	if got := memoryMessages(mem); strings.Join(got, ",") != "error after" {
+		t.Errorf("mem after capture: %v", got)
+	}
+	if got := memoryMessages(audit); strings.Join(got, ",") != "audit after" {
+		t.Errorf("audit after capture: %v", got)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	logger := log4j.NewLogger()
+	ft := &fakeT{TB: t}
+	logs := CaptureLogger(t, logger)
+	logs.t = ft
+
+	logger.Info("connect ok")
+	logger.WarnTag("db", "slow query")
+	logger.EmptyLine(log4j.INFO, "")
+
+	logs.Expect(log4j.INFO, "connect")
+	logs.ExpectTag("db", "slow")
+	logs.ExpectNoError()
+	logs.ExpectNone(log4j.ERROR)
+	if len(ft.errors) != 0 {
+		t.Fatalf("unexpected failures: %v", ft.errors)
+	}
+	if got := len(logs.Find(log4j.INFO, "")); got != 1 {
+		t.Errorf("Find(INFO) = %d, empty line should be ignored", got)
+	}
+
+	logs.ExpectError("connect")
+	logs.ExpectTag("db", "fast")
+	logs.ExpectNone(log4j.WARNING)
+	if len(ft.errors) != 3 {
+		t.Fatalf("expect 3 failures, got %v", ft.errors)
+	}
+	if !strings.Contains(ft.errors[0], "[INFO]") || !strings.Contains(ft.errors[0], "connect ok") ||
+		!strings.Contains(ft.errors[0], "[db] [WARN]") {
+		t.Errorf("failure should list captured logs: %s", ft.errors[0])
+	}
+
+	logger.Error("disk full")
+	ft.errors = nil
+	logs.ExpectNoError()
+	if len(ft.errors) != 1 {
+		t.Errorf("ExpectNoError: %v", ft.errors)
+	}
+
+	logs.Reset()
+	ft.errors = nil
+	logs.ExpectNoError()
+	if len(logs.Records()) != 0 || len(ft.errors) != 0 {
+		t.Errorf("after Reset: %d records, %v", len(logs.Records()), ft.errors)
+	}
+	logs.ExpectError("")
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "(no log)") {
+		t.Errorf("empty dump: %v", ft.errors)
+	}
+}
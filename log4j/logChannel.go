@@ -0,0 +1,124 @@
+package log4j
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 缓冲区满时的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 阻塞直到有空位(默认; HttpLogWriter默认为OverflowDropOldest)
+	OverflowTimeout                          // 阻塞至超时后丢弃该条日志
+	OverflowDropNewest                       // 直接丢弃该条日志
+	OverflowDropOldest                       // 丢弃缓冲区中最旧的一条日志
+)
+
+var overflowPolicyNames = [...]string{"block", "timeout", "dropNewest", "dropOldest"}
+
+func (p OverflowPolicy) String() string {
+	if p < 0 || int(p) >= len(overflowPolicyNames) {
+		return "UNKNOWN"
+	}
+	return overflowPolicyNames[p]
+}
+
+func parseOverflowPolicy(str string) (OverflowPolicy, bool) {
+	for i, name := range overflowPolicyNames {
+		if strings.EqualFold(name, str) {
+			return OverflowPolicy(i), true
+		}
+	}
+	return 0, false
+}
+
+// LogWriter的日志缓冲区, 按OverflowPolicy处理写满的情况, 并统计丢弃的日志数;
+// 嵌入在各LogWriter中, 提供SetOverflow、GetDropped
+type logChannel struct {
+	ch      chan *LogRecord
+	policy  OverflowPolicy
+	timeout time.Duration
+
+	dropped     int64 // 累计丢弃数
+	notNotified int64 // 尚未输出提示的丢弃数
+}
+
+func newLogChannel(size int) *logChannel {
+	return &logChannel{ch: make(chan *LogRecord, size)}
+}
+
+func (c *logChannel) put(rec *LogRecord) {
+	select {
+	case c.ch <- rec:
+		return
+	default:
+	}
+
+	switch c.policy {
+	case OverflowTimeout:
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		select {
+		case c.ch <- rec:
+		case <-timer.C:
+			c.drop()
+		}
+
+	case OverflowDropNewest:
+		c.drop()
+
+	case OverflowDropOldest:
+		select {
+		case <-c.ch:
+			c.drop()
+		default:
+		}
+		select {
+		case c.ch <- rec:
+		default:
+			c.drop() // 并发写入又被占满
+		}
+
+	default:
+		c.ch <- rec
+	}
+}
+
+func (c *logChannel) drop() {
+	atomic.AddInt64(&c.dropped, 1)
+	atomic.AddInt64(&c.notNotified, 1)
+}
+
+// 缓冲区满时的处理策略, timeout仅对OverflowTimeout有效
+func (c *logChannel) SetOverflow(policy OverflowPolicy, timeout time.Duration) {
+	c.policy, c.timeout = policy, timeout
+}
+
+// 因缓冲区满而丢弃的日志数
+func (c *logChannel) GetDropped() int64 {
+	return atomic.LoadInt64(&c.dropped)
+}
+
+// 缓冲区已清空且有未提示的丢弃时, 返回一条提示日志
+func (c *logChannel) droppedNotice() *LogRecord {
+	if len(c.ch) > 0 || atomic.LoadInt64(&c.notNotified) == 0 {
+		return nil
+	}
+	n := atomic.SwapInt64(&c.notNotified, 0)
+	if n == 0 {
+		return nil
+	}
+	return &LogRecord{
+		Level:   WARNING,
+		Created: time.Now(),
+		Source:  "log4j",
+		Message: fmt.Sprintf("%d log records dropped, buffer was full (overflow policy: %s)", n, c.policy),
+	}
+}
+
+func (c *logChannel) close() {
+	close(c.ch)
+}
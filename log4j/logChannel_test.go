@@ -0,0 +1,147 @@
+package log4j
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 在gate关闭前阻塞所有写入, 模拟写不动的输出端
+type blockedWriter struct {
+	gate chan struct{}
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *blockedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockedWriter) lines() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return strings.Split(strings.TrimSuffix(w.buf.String(), "\n"), "\n")
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	cases := []struct {
+		policy  OverflowPolicy
+		timeout time.Duration
+		blocked bool     // 第4条是否阻塞到输出端恢复
+		want    []string // 输出端恢复后的输出
+		dropped int64
+	}{
+		{OverflowBlock, 0, true, []string{"1", "2", "3", "4"}, 0},
+		{OverflowTimeout, 20 * time.Millisecond, false, []string{"1", "2", "3", "1 log records dropped, buffer was full (overflow policy: timeout)"}, 1},
+		{OverflowDropNewest, 0, false, []string{"1", "2", "3", "1 log records dropped, buffer was full (overflow policy: dropNewest)"}, 1},
+		{OverflowDropOldest, 0, false, []string{"1", "3", "4", "1 log records dropped, buffer was full (overflow policy: dropOldest)"}, 1},
+	}
+
+	defer func(length int) { LogBufferLength = length }(LogBufferLength)
+	LogBufferLength = 2
+
+	for _, c := range cases {
+		out := &blockedWriter{gate: make(chan struct{})}
+		w := NewConsoleLogWriterTo(TRACE, out)
+		w.SetFormat("%M")
+		w.SetOverflow(c.policy, c.timeout)
+
+		// 第1条被取出后阻塞在输出端, 第2、3条占满缓冲区
+		w.LogWrite(testRecord("1"))
+		for deadline := time.Now().Add(time.Second); len(w.ch) > 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		w.LogWrite(testRecord("2"))
+		w.LogWrite(testRecord("3"))
+
+		start, done := time.Now(), make(chan bool)
+		go func() {
+			w.LogWrite(testRecord("4"))
+			close(done)
+		}()
+		select {
+		case <-done:
+			if c.blocked {
+				t.Errorf("%s: LogWrite returned while buffer full", c.policy)
+			}
+			if elapsed := time.Since(start); elapsed < c.timeout {
+				t.Errorf("%s: LogWrite returned after %s, before timeout %s", c.policy, elapsed, c.timeout)
+			}
+		case <-time.After(200 * time.Millisecond):
+			if !c.blocked {
+				t.Errorf("%s: LogWrite blocked", c.policy)
+			}
+		}
+
+		close(out.gate)
+		<-done
+		w.Close()
+
+		if got := w.GetDropped(); got != c.dropped {
+			t.Errorf("%s: GetDropped() = %d, want %d", c.policy, got, c.dropped)
+		}
+		got := out.lines()
+		for i := range got {
+			got[i] = strings.TrimSpace(got[i])
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("%s: output %q, want %q", c.policy, got, c.want)
+		}
+	}
+}
+
+// 提示在缓冲区清空后输出一次, 累计数不清零
+func TestDroppedNotice(t *testing.T) {
+	c := newLogChannel(1)
+	c.SetOverflow(OverflowDropNewest, 0)
+	c.put(testRecord("a"))
+	c.put(testRecord("b"))
+	c.put(testRecord("c"))
+
+	if notice := c.droppedNotice(); notice != nil {
+		t.Errorf("notice before buffer empty: %v", notice.Message)
+	}
+	<-c.ch
+	notice := c.droppedNotice()
+	if notice == nil || notice.Level != WARNING || !strings.HasPrefix(notice.Message, "2 log records dropped") {
+		t.Fatalf("got notice %+v", notice)
+	}
+	if notice := c.droppedNotice(); notice != nil {
+		t.Errorf("notice repeated: %v", notice.Message)
+	}
+	if c.GetDropped() != 2 {
+		t.Errorf("GetDropped() = %d", c.GetDropped())
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowTimeout, OverflowDropNewest, OverflowDropOldest} {
+		if got, ok := parseOverflowPolicy(strings.ToLower(policy.String())); !ok || got != policy {
+			t.Errorf("parseOverflowPolicy(%s) = %s, %v", policy, got, ok)
+		}
+	}
+	if _, ok := parseOverflowPolicy("drop"); ok {
+		t.Error("expect unsupported policy")
+	}
+
+	// 只配置overflowTimeout时为OverflowTimeout; OverflowTimeout未配置时长时默认100ms
+	for _, c := range []struct {
+		prop    LogProperty
+		policy  OverflowPolicy
+		timeout time.Duration
+	}{
+		{LogProperty{}, OverflowBlock, 0},
+		{LogProperty{OverflowTimeout: time.Second}, OverflowTimeout, time.Second},
+		{LogProperty{Overflow: OverflowTimeout}, OverflowTimeout, 100 * time.Millisecond},
+		{LogProperty{Overflow: OverflowDropOldest}, OverflowDropOldest, 0},
+	} {
+		if policy, timeout := c.prop.overflowPolicy(); policy != c.policy || timeout != c.timeout {
+			t.Errorf("%+v: got %s %s, want %s %s", c.prop, policy, timeout, c.policy, c.timeout)
+		}
+	}
+}
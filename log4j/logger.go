@@ -0,0 +1,134 @@
+package log4j
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// 包级别的Info、Error等函数使用的默认实例
+func Default() *Logger {
+	return defaultLogger
+}
+
+func (p *Logger) With(kvs ...interface{}) *Entry {
+	return (&Entry{logger: p}).With(kvs...)
+}
+
+func (p *Logger) WithContext(ctx context.Context) *Entry {
+	return &Entry{logger: p, ctx: ctx}
+}
+
+func (p *Logger) Tags(tags ...string) *Entry {
+	return &Entry{logger: p, tags: tags}
+}
+
+func (p *Logger) Log(logBuffer LogBuffer) {
+	p.addLogBuffer("", false, logBuffer)
+}
+
+func (p *Logger) LogIfError(logBuffer LogBuffer) {
+	p.addLogBuffer("", true, logBuffer)
+}
+
+func (p *Logger) LogTag(tag string, logBuffer LogBuffer) {
+	p.addLogBuffer(tag, false, logBuffer)
+}
+
+func (p *Logger) LogTagIfError(tag string, logBuffer LogBuffer) {
+	p.addLogBuffer(tag, true, logBuffer)
+}
+
+func (p *Logger) Trace(arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(TRACE, false, "", nil, arg0, args...)
+}
+
+func (p *Logger) TraceTag(tag string, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(TRACE, false, tag, nil, arg0, args...)
+}
+
+func (p *Logger) Debug(arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(DEBUG, false, "", nil, arg0, args...)
+}
+
+func (p *Logger) DebugTag(tag string, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(DEBUG, false, tag, nil, arg0, args...)
+}
+
+func (p *Logger) Info(arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(INFO, false, "", nil, arg0, args...)
+}
+
+func (p *Logger) InfoTag(tag string, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(INFO, false, tag, nil, arg0, args...)
+}
+
+func (p *Logger) Warn(arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(WARNING, false, "", nil, arg0, args...))
+}
+
+func (p *Logger) WarnTag(tag string, arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(WARNING, false, tag, nil, arg0, args...))
+}
+
+func (p *Logger) Error(arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(ERROR, false, "", nil, arg0, args...))
+}
+
+func (p *Logger) ErrorTag(tag string, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(ERROR, false, tag, nil, arg0, args...)
+}
+
+// error log with stack info
+func (p *Logger) ErrorStack(arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(ERROR, true, "", nil, arg0, args...))
+}
+
+func (p *Logger) ErrorTagStack(tag string, arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(ERROR, true, tag, nil, arg0, args...))
+}
+
+// fatal log with stack info, 之后关闭所有LogWriter(写完缓冲区中的日志)并退出进程
+func (p *Logger) Fatal(arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(FATAL, true, "", nil, arg0, args...)
+	p.exit()
+}
+
+func (p *Logger) FatalTag(tag string, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(FATAL, true, tag, nil, arg0, args...)
+	p.exit()
+}
+
+func (p *Logger) exit() {
+	p.Close()
+	os.Exit(1)
+}
+
+func (p *Logger) TraceCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(TRACE, false, "", p.WithContext(ctx), arg0, args...)
+}
+
+func (p *Logger) DebugCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(DEBUG, false, "", p.WithContext(ctx), arg0, args...)
+}
+
+func (p *Logger) InfoCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(INFO, false, "", p.WithContext(ctx), arg0, args...)
+}
+
+func (p *Logger) WarnCtx(ctx context.Context, arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(WARNING, false, "", p.WithContext(ctx), arg0, args...))
+}
+
+func (p *Logger) ErrorCtx(ctx context.Context, arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(ERROR, false, "", p.WithContext(ctx), arg0, args...))
+}
+
+func (p *Logger) ErrorStackCtx(ctx context.Context, arg0 interface{}, args ...interface{}) error {
+	return errors.New(p.addLogArgs(ERROR, true, "", p.WithContext(ctx), arg0, args...))
+}
+
+func (p *Logger) FatalCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	p.addLogArgs(FATAL, true, "", p.WithContext(ctx), arg0, args...)
+	p.exit()
+}
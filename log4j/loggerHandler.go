@@ -2,35 +2,48 @@ package log4j
 
 import (
 	"bytes"
-	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"runtime"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
 
-type loggerHandler struct {
-	logWriterMap       map[string]logWriter
+// 日志实例, 持有各tag对应的LogWriter; 包级别的Info、Error等函数使用默认实例Default()
+type Logger struct {
+	logWriterMap       map[string]LogWriter
 	lock               sync.RWMutex
 	defaultLogFilePath string
+	levelRules         *levelRules               // 配置中<logger>按包/函数路径设置的级别
+	levelOverrides     map[string]*levelOverride // SetLevel等运行时修改的LogWriter级别
+	routes             map[string]*routeRule     // 私有LogWriter的tag -> 额外的路由规则
+
+	configFile string        // 最近一次加载的配置文件, Reload时重新加载
+	watchStop  chan struct{} // 停止WatchConfiguration
+}
+
+// 不含任何LogWriter的日志实例, 通过LoadConfiguration或SetLogWriter添加; 无LogWriter时INFO及以上级别输出到stdout/stderr
+func NewLogger() *Logger {
+	return &Logger{
+		lock:         sync.RWMutex{},
+		logWriterMap: map[string]LogWriter{},
+	}
 }
 
-func newDefaultLogger(lvl Level) *loggerHandler {
-	return &loggerHandler{
+func newDefaultLogger(lvl Level) *Logger {
+	return &Logger{
 		lock: sync.RWMutex{},
-		logWriterMap: map[string]logWriter{
+		logWriterMap: map[string]LogWriter{
 			"stdout": NewConsoleLogWriter(lvl)},
 	}
 }
 
 // Close all open loggers
-func (p *loggerHandler) close() {
+func (p *Logger) Close() {
+	p.StopWatch()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -40,58 +53,185 @@ func (p *loggerHandler) close() {
 	}
 }
 
-func (p *loggerHandler) closeByTag(logTag string) {
+func (p *Logger) CloseByTag(logTag string) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
 	if logWriter, ok := p.logWriterMap[logTag]; ok {
 		logWriter.Close()
 		delete(p.logWriterMap, logTag)
+		delete(p.levelOverrides, logTag)
 	}
 }
 
-func (p *loggerHandler) getLogFilePath() string {
-	return p.defaultLogFilePath
+// 替换tag对应的LogWriter, 旧的LogWriter会被关闭
+func (p *Logger) SetLogWriter(tag string, logWriter LogWriter) {
+	p.lock.Lock()
+	old := p.logWriterMap[tag]
+	p.logWriterMap[tag] = logWriter
+	p.lock.Unlock()
+
+	if old != nil && old != logWriter {
+		old.Close()
+	}
 }
 
-func (p *loggerHandler) getLogWriterMap() map[string]logWriter {
-	p.lock.RLock()
-	defer p.lock.RUnlock()
-	return p.logWriterMap
+// 用logWriter替换全部LogWriter, 并暂停<logger>级别规则及私有LogWriter的路由规则, 使所有日志都交给logWriter;
+// 原LogWriter不会被关闭, 调用restore换回原来的LogWriter及规则. 用于测试中捕获日志(见log4jtest)
+func (p *Logger) Intercept(tag string, logWriter LogWriter) (restore func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	logWriterMap, levelRules, routes := p.logWriterMap, p.levelRules, p.routes
+	p.logWriterMap = map[string]LogWriter{tag: logWriter}
+	p.levelRules, p.routes = nil, nil
+
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		p.logWriterMap, p.levelRules, p.routes = logWriterMap, levelRules, routes
+	}
 }
 
-func (p *loggerHandler) addLogString(runtimeSkip int, lvl Level, withStack bool, tag string, format string, args ...interface{}) {
+func (p *Logger) GetLogFilePath() string {
+	return p.defaultLogFilePath
+}
+
+// 返回格式化后的日志内容(不含堆栈信息)
+func (p *Logger) addLogString(runtimeSkip int, lvl Level, withStack bool, tag string, entry *Entry, format string, args ...interface{}) string {
 
-	src, msg := getSrcAndMsg(runtimeSkip, withStack, format, args...)
+	src, file, msg, stack := getSrcAndMsg(runtimeSkip, withStack, format, args...)
 
-	rec := &logRecord{
+	rec := &LogRecord{
 		Level:   lvl,
 		Created: time.Now(),
 		Source:  src,
+		File:    file,
 		Message: msg,
+		Stack:   stack,
 	}
+	entry.fill(rec)
 
-	p.print(rec, lvl, tag)
+	tags := routeTags(tag, entry)
+	if len(tags) > 0 {
+		rec.Tag = tags[0]
+	}
+	p.print(rec, lvl, tags)
+	return msg
 }
 
-func (p *loggerHandler) print(rec *logRecord, lvl Level, tag string) {
-	logWriterMap := p.getLogWriterMap()
+// Log/LogTag等入口, 比直接调用addLogString多一层调用; FATAL级别的LogBuffer只输出, 不退出进程
+func (p *Logger) addLogBuffer(tag string, onlyError bool, logBuffer LogBuffer) {
+	lv := logBuffer.GetLogLevel()
+	if onlyError && lv < ERROR {
+		return
+	}
+
+	logTxt, skip := logBuffer.String(), logBuffer.RuntimeSkip(RUNTIME_SKIP)+1
+	switch lv {
+	case TRACE, DEBUG, INFO, WARNING:
+		p.addLogString(skip, lv, false, tag, logBufferEntry(logBuffer), logTxt)
+	default:
+		p.addLogString(skip, lv, logBuffer.PrintStack(), tag, logBufferEntry(logBuffer), logTxt)
+	}
+}
 
-	// 指定tag 且 对应的tag文件存在且私有, 只写私有
-	if tag != "" {
+// Debug/Info/Warn/Error等入口的参数解析, 返回日志内容用于构造error
+func (p *Logger) addLogArgs(lvl Level, withStack bool, tag string, entry *Entry, arg0 interface{}, args ...interface{}) string {
+	switch first := arg0.(type) {
+	case string:
+		return p.addLogString(RUNTIME_SKIP+1, lvl, withStack, tag, entry, first, args...)
+	case func() (log string, src string):
+		logString, src := first()
+		p.addLogFunc(lvl, withStack, tag, entry, logString, src)
+		return logString
+	default:
+		if args != nil {
+			return p.addLogString(RUNTIME_SKIP+1, lvl, withStack, tag, entry, "%+v", append([]interface{}{arg0}, args))
+		} else {
+			return p.addLogString(RUNTIME_SKIP+1, lvl, withStack, tag, entry, "%+v", arg0)
+		}
+	}
+}
+
+// 持有读锁写日志, 保证替换logWriterMap后不会再写入已关闭的LogWriter
+func (p *Logger) print(rec *LogRecord, lvl Level, tags []string) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	logWriterMap := p.logWriterMap
+	if rec != nil && needGoroutine(logWriterMap) {
+		rec.Goroutine = goroutineId()
+	}
+
+	// 调用方匹配<logger>且低于其级别时不输出, 运行时修改过级别的LogWriter除外; 否则再按各LogWriter的级别判断
+	gated := false
+	if rec != nil {
+		ruleLevel, matched := p.levelRules.match(rec.Source)
+		gated = matched && lvl < ruleLevel
+	}
+	if gated && len(p.levelOverrides) == 0 {
+		return
+	}
+	now := time.Time{}
+	if len(p.levelOverrides) > 0 {
+		now = time.Now()
+	}
+	// 被<logger>或运行时级别过滤的日志不再输出到stdout/stderr
+	suppressed := gated
+	enabled := func(tagName string, logWriter LogWriter) bool {
+		minLevel, ok := p.overrideLevel(tagName, now)
+		if !ok {
+			return !gated && lvl >= logWriter.GetLevel()
+		}
+		if lvl < minLevel {
+			suppressed = true
+			return false
+		}
+		return true
+	}
+
+	// 每个tag: 对应的私有LogWriter存在(或其tags匹配)时只写私有, 除非配置了mirror; 都不匹配时写共享
+	written := []string(nil)
+	toShared := len(tags) == 0
+	for _, tag := range tags {
+		privateHit := false
 		for tagName, logWriter := range logWriterMap {
-			if tagName == tag && logWriter.IsPrivate() {
-				if lvl >= logWriter.GetLevel() {
-					logWriter.LogWrite(rec)
-				}
-				return
+			if !logWriter.IsPrivate() {
+				continue
+			}
+			route := p.routes[tagName]
+			if tagName != tag && !route.matchTag(tag) {
+				continue
+			}
+
+			privateHit = true
+			if route != nil && route.mirror {
+				toShared = true
+			}
+			if !containsString(written, tagName) && enabled(tagName, logWriter) {
+				logWriter.LogWrite(rec)
+				written = append(written, tagName)
 			}
 		}
+		if !privateHit {
+			toShared = true
+		}
+	}
+	if !toShared {
+		return
 	}
 
 	isPrinted := false
-	for _, logWriter := range logWriterMap {
-		if lvl >= logWriter.GetLevel() && !logWriter.IsPrivate() {
+	for tagName, logWriter := range logWriterMap {
+		if logWriter.IsPrivate() {
+			// 配置了untaggedLevel的私有LogWriter也接收写入共享LogWriter的日志
+			if route := p.routes[tagName]; route != nil && route.untagged && !containsString(written, tagName) &&
+				lvl >= route.untaggedLevel && enabled(tagName, logWriter) {
+				logWriter.LogWrite(rec)
+				isPrinted = true
+			}
+		} else if enabled(tagName, logWriter) {
 			logWriter.LogWrite(rec)
 			isPrinted = true
 		}
@@ -110,7 +250,7 @@ func (p *loggerHandler) print(rec *logRecord, lvl Level, tag string) {
 	    }
 	}*/
 
-	if !isPrinted {
+	if !isPrinted && !suppressed {
 		if lvl == INFO {
 			_, _ = fPrintFormatLog(os.Stdout, defaultFormat, rec)
 		} else if lvl > INFO {
@@ -119,130 +259,34 @@ func (p *loggerHandler) print(rec *logRecord, lvl Level, tag string) {
 	}
 }
 
-func (p *loggerHandler) addLogFunc(lvl Level, withStack bool, tag string, logString string, src string) {
-	rec := &logRecord{
+func (p *Logger) addLogFunc(lvl Level, withStack bool, tag string, entry *Entry, logString string, src string) {
+	rec := &LogRecord{
 		Level:   lvl,
 		Created: time.Now(),
 		Source:  src,
 		Message: logString,
 	}
+	entry.fill(rec)
 
-	p.print(rec, lvl, tag)
-}
-
-func (p *loggerHandler) addEmptyLine(lvl Level, tag string) {
-	p.print(nil, lvl, tag)
-}
-
-// Load XML configuration
-func (p *loggerHandler) loadConfiguration(filename string) {
-	p.close()
-
-	// Open the configuration file
-	fd, err := os.Open(filename)
-	if err != nil {
-		printlnIO(os.Stderr, "ERROR", "open file:%s err: %s", filename, err.Error())
-		os.Exit(1)
+	tags := routeTags(tag, entry)
+	if len(tags) > 0 {
+		rec.Tag = tags[0]
 	}
+	p.print(rec, lvl, tags)
+}
 
-	contents, err := ioutil.ReadAll(fd)
-	if err != nil {
-		printlnIO(os.Stderr, "ERROR", "read file all:%s err: %s", filename, err.Error())
-		os.Exit(1)
-	}
-
-	xc := new(xmlLoggerConfig)
-	if err := xml.Unmarshal(contents, xc); err != nil {
-		printlnIO(os.Stderr, "ERROR", "xml.Unmarshal err: %s", err.Error())
-		os.Exit(1)
-	}
-
-	p.lock.Lock()
-	defer p.lock.Unlock()
-
-	for _, xmlFilter := range xc.Filter {
-
-		// Check required children
-		if xmlFilter.Enabled == "" {
-			printlnIO(os.Stderr, "ERROR", "log filter property:enabled not found")
-			os.Exit(1)
-
-		} else if xmlFilter.Enabled == "false" {
-			continue
-		}
-
-		if xmlFilter.Tag == "" {
-			printlnIO(os.Stderr, "ERROR", "log filter child:<tag> not found")
-			os.Exit(1)
-
-		} else if _, ok := p.logWriterMap[xmlFilter.Tag]; ok {
-			printlnIO(os.Stderr, "ERROR", "log filter child:<tag>'s value repeat")
-			os.Exit(1)
-		}
-
-		if xmlFilter.Type == "" {
-			printlnIO(os.Stderr, "ERROR", "log filter child:<type> not found")
-			os.Exit(1)
-		}
-
-		if xmlFilter.Level == "" {
-			printlnIO(os.Stderr, "ERROR", "log filter child:<level> not found")
-			os.Exit(1)
-		}
-
-		var lvl Level
-		switch xmlFilter.Level {
-		case "DEBUG":
-			lvl = DEBUG
-		case "INFO":
-			lvl = INFO
-		case "WARNING":
-			lvl = WARNING
-		case "ERROR":
-			lvl = ERROR
-		default:
-			printlnIO(os.Stderr, "ERROR", "unsupported filter child:<level>'s value: %s", xmlFilter.Level)
-			os.Exit(1)
-		}
-
-		logWriter, err := logWriter(nil), error(nil)
-		switch xmlFilter.Type {
-		case "console":
-			logWriter, err = xmlToConsoleLogWriter(lvl, xmlFilter.Property)
-		case "file":
-			logWriter, err = xmlToFileLogWriter(xmlFilter.Tag, lvl, xmlFilter.Property)
-		default:
-			printlnIO(os.Stderr, "ERROR", "unsupported filter child:<type>'s value: %s", xmlFilter.Type)
-			os.Exit(1)
-		}
-
-		if err != nil {
-			printlnIO(os.Stderr, "ERROR", err.Error())
-			os.Exit(1)
-
-		} else if p.defaultLogFilePath == "" && xmlFilter.Type == "file" {
-			fileLogWriter := logWriter.(*FileLogWriter)
-			pathIndex := strings.LastIndex(fileLogWriter.GetFilename(), "/")
-			p.defaultLogFilePath = fileLogWriter.GetFilename()[0:pathIndex]
-		}
-
-		p.logWriterMap[xmlFilter.Tag] = logWriter
-	}
+func (p *Logger) EmptyLine(lvl Level, tag string) {
+	p.print(nil, lvl, routeTags(tag, nil))
 }
 
-func (p *loggerHandler) addFileLoggerIfNotExist(tag string, lv Level, prop *LogProperty) (isExist bool) {
+func (p *Logger) AddFileLoggerIfNotExist(tag string, lv Level, prop *LogProperty) (isExist bool) {
 
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
 	if _, ok := p.logWriterMap[tag]; !ok {
-		if flw, err := NewFileLogWriter(tag, lv, prop.Filename, prop.Rotate, prop.KeepDay); err == nil {
-			flw.SetFormat(prop.Format)
-			flw.SetRotateLines(prop.MaxLines)
-			flw.SetRotateSize(prop.Maxsize)
-			flw.SetRotateDaily(prop.Daily)
-			flw.SetPrivate(prop.Private)
-			p.logWriterMap[tag] = flw
+		if flw, err := newFileLogWriterByProperty(tag, lv, prop); err == nil {
+			p.logWriterMap[tag] = wrapLogWriter(flw, prop)
 			return true
 		} else {
 			return false
@@ -254,23 +298,19 @@ func (p *loggerHandler) addFileLoggerIfNotExist(tag string, lv Level, prop *LogP
 
 var newLine = []byte("\n")
 
-func getSrcAndMsg(runtimeSkip int, withStack bool, format string, args ...interface{}) (string, string) {
+func getSrcAndMsg(runtimeSkip int, withStack bool, format string, args ...interface{}) (src, file, msg, stack string) {
 
 	// Determine caller func
-	pc, _, lineno, ok := runtime.Caller(runtimeSkip)
-	src := ""
+	pc, path, lineno, ok := runtime.Caller(runtimeSkip)
 	if ok {
 		src = fmt.Sprintf("%s:%d", runtime.FuncForPC(pc).Name(), lineno)
+		file = path + ":" + strconv.Itoa(lineno)
 	}
 
-	msg := bytesBufferPool.Get().(*bytes.Buffer)
-	msg.Reset()
-	defer bytesBufferPool.Put(msg)
-
 	if len(args) > 0 {
-		msg.WriteString(fmt.Sprintf(format, args...))
+		msg = fmt.Sprintf(format, args...)
 	} else {
-		msg.WriteString(format)
+		msg = format
 	}
 
 	// 堆栈信息
@@ -280,115 +320,50 @@ func getSrcAndMsg(runtimeSkip int, withStack bool, format string, args ...interf
 
 		stackBs := bs[:runtime.Stack(bs, false)]
 
-		msg.WriteByte('\n')
+		out := bytesBufferPool.Get().(*bytes.Buffer)
+		out.Reset()
+		defer bytesBufferPool.Put(out)
+
 		if runtimeSkip <= 0 {
-			msg.Write(stackBs) // 不跳过
+			out.Write(stackBs) // 不跳过
 		} else {
 			// 第一行为goroutine标识，从第2行起，每2行是一个层级信息
 			lineSlice := bytes.Split(stackBs, newLine)
 			if size := len(lineSlice); size > runtimeSkip*2+1 {
-				msg.Write(lineSlice[0])
+				out.Write(lineSlice[0])
 				for i := 1 + runtimeSkip*2; i < size; i++ {
-					msg.WriteByte('\n')
-					msg.Write(lineSlice[i])
+					out.WriteByte('\n')
+					out.Write(lineSlice[i])
 				}
 			} else {
-				msg.Write(stackBs) // should not happen
+				out.Write(stackBs) // should not happen
 			}
 		}
+		stack = out.String()
 	}
-	return src, msg.String()
+	return src, file, msg, stack
 }
 
-func xmlToConsoleLogWriter(lvl Level, props []xmlProperty) (*ConsoleLogWriter, error) {
-
-	format := "[%D %T] [%L] (%S) %M"
-
-	for _, prop := range props {
-		switch prop.Name {
-		case "format":
-			format = strings.Trim(prop.Value, " \r\n")
-		default:
-			return nil, fmt.Errorf("unsupported filter property: %s", prop.Name)
-		}
-	}
-
-	console := NewConsoleLogWriter(lvl)
-	console.SetFormat(format)
-	return console, nil
-}
-
-// Parse a number with K/M/G suffixes based on thousands (1000) or 2^10 (1024)
-func strToNumSuffix(str string, multiple int) int {
-	num := 1
-	if len(str) > 1 {
-		switch str[len(str)-1] {
-		case 'G', 'g':
-			num *= multiple
-			fallthrough
-		case 'M', 'm':
-			num *= multiple
-			fallthrough
-		case 'K', 'k':
-			num *= multiple
-			str = str[0 : len(str)-1]
+func needGoroutine(logWriterMap map[string]LogWriter) bool {
+	for _, logWriter := range logWriterMap {
+		if g, ok := unwrapLogWriter(logWriter).(goroutineLogWriter); ok && g.needGoroutine() {
+			return true
 		}
 	}
-	parsed, _ := strconv.Atoi(str)
-	return parsed * num
+	return false
 }
 
-func xmlToFileLogWriter(tag string, lvl Level, props []xmlProperty) (*FileLogWriter, error) {
-
-	file := ""
-	format := "[%D %T] [%L] (%S) %M"
-	maxLines := 0
-	maxSize := 0
-	daily := false
-	rotate := false
-	private := false
-	keepDay := int64(0)
-
-	// Parse properties
-	for _, prop := range props {
-		switch prop.Name {
-		case "filename":
-			file = strings.Trim(prop.Value, " \r\n")
-		case "format":
-			format = strings.Trim(prop.Value, " \r\n")
-		case "maxlines":
-			maxLines = strToNumSuffix(strings.Trim(prop.Value, " \r\n"), 1000)
-		case "maxsize":
-			maxSize = strToNumSuffix(strings.Trim(prop.Value, " \r\n"), 1024)
-		case "daily":
-			daily = strings.Trim(prop.Value, " \r\n") != "false"
-		case "rotate":
-			rotate = strings.Trim(prop.Value, " \r\n") != "false"
-		case "private":
-			private = strings.Trim(prop.Value, " \r\n") != "false"
-		case "keepDay":
-			keepDay = int64(strToNumSuffix(strings.Trim(prop.Value, " \r\n"), 1000))
-		default:
-			return nil, fmt.Errorf("unsupported property: %s", prop.Name)
-		}
-	}
-
-	// Check properties
-	if len(file) == 0 {
-		return nil, errors.New("missing property: filename")
-	}
+var goroutinePrefix = []byte("goroutine ")
 
-	if flw, err := NewFileLogWriter(tag, lvl, file, rotate, keepDay); err == nil {
-		flw.SetFormat(format)
-		flw.SetRotateLines(maxLines)
-		flw.SetRotateSize(maxSize)
-		flw.SetRotateDaily(daily)
-		flw.SetPrivate(private)
-		return flw, nil
-	} else {
-		return nil, err
+// 从当前goroutine的堆栈首行"goroutine 18 [running]:"中解析id
+func goroutineId() int64 {
+	var buf [64]byte
+	bs := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], goroutinePrefix)
+	if i := bytes.IndexByte(bs, ' '); i > 0 {
+		bs = bs[:i]
 	}
-
+	id, _ := strconv.ParseInt(string(bs), 10, 64)
+	return id
 }
 
 func printlnIO(ioWriter io.Writer, typ string, format string, args ...interface{}) {
@@ -0,0 +1,133 @@
+package log4j
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoggerIsolated(t *testing.T) {
+	w1, w2 := &testLogWriter{level: INFO}, &testLogWriter{level: DEBUG}
+	logger1, logger2 := NewLogger(), NewLogger()
+	logger1.SetLogWriter("app", w1)
+	logger2.SetLogWriter("app", w2)
+
+	logger1.Info("one")
+	logger1.Debug("one debug")
+	logger2.Debug("two debug")
+	logger2.WithContext(ContextWith(context.Background(), "traceId", "t-2")).Info("two ctx")
+
+	if got := w1.messages(); !reflect.DeepEqual(got, []string{"one"}) {
+		t.Errorf("logger1: %v", got)
+	}
+	if got := w2.messages(); !reflect.DeepEqual(got, []string{"two debug", "two ctx"}) {
+		t.Errorf("logger2: %v", got)
+	}
+	if got := w2.records()[1].Context; !reflect.DeepEqual(got, []Field{{"traceId", "t-2"}}) {
+		t.Errorf("logger2 context: %v", got)
+	}
+
+	// 运行时级别、关闭只影响各自的实例
+	if err := logger1.SetLevel("app", DEBUG); err != nil {
+		t.Fatal(err)
+	}
+	if levels := logger2.GetLevels(); levels["app"] != DEBUG {
+		t.Errorf("logger2 levels: %v", levels)
+	}
+	logger2.SetLogWriter("app", &testLogWriter{level: ERROR})
+	if levels := logger1.GetLevels(); levels["app"] != DEBUG {
+		t.Errorf("logger1 levels: %v", levels)
+	}
+
+	logger1.Close()
+	if got := w1.messages(); len(got) != 1 {
+		t.Errorf("closed logger1 got %v", got)
+	}
+	if Default() == logger1 || Default() == logger2 {
+		t.Error("NewLogger returned the default logger")
+	}
+}
+
+func TestLevelNames(t *testing.T) {
+	cases := []struct {
+		lvl   Level
+		short string
+		name  string
+	}{
+		{TRACE, "TRAC", "TRACE"},
+		{DEBUG, "DEBG", "DEBUG"},
+		{INFO, "INFO", "INFO"},
+		{WARNING, "WARN", "WARNING"},
+		{ERROR, "EROR", "ERROR"},
+		{FATAL, "FATL", "FATAL"},
+	}
+	for _, c := range cases {
+		if c.lvl.String() != c.short {
+			t.Errorf("%d.String() = %s, want %s", c.lvl, c.lvl.String(), c.short)
+		}
+		if lvl, ok := parseLevel(c.name); !ok || lvl != c.lvl {
+			t.Errorf("parseLevel(%s) = %d, %v", c.name, lvl, ok)
+		}
+	}
+	for _, lvl := range []Level{-1, FATAL + 1} {
+		if lvl.String() != "UNKNOWN" {
+			t.Errorf("%d.String() = %s", lvl, lvl.String())
+		}
+	}
+}
+
+func TestTraceLevel(t *testing.T) {
+	trace, debug := &testLogWriter{level: TRACE}, &testLogWriter{level: DEBUG}
+	logger := NewLogger()
+	logger.SetLogWriter("trace", trace)
+	logger.SetLogWriter("debug", debug)
+
+	logger.Trace("trace %d", 1)
+	logger.TraceTag("tag", "trace tag")
+	logger.Debug("debug")
+
+	if got := trace.messages(); strings.Join(got, ",") != "trace 1,trace tag,debug" {
+		t.Errorf("trace writer: %v", got)
+	}
+	if got := debug.messages(); strings.Join(got, ",") != "debug" {
+		t.Errorf("debug writer: %v", got)
+	}
+	if recs := trace.records(); recs[0].Level != TRACE || recs[1].Tag != "tag" {
+		t.Errorf("records: %+v %+v", *recs[0], *recs[1])
+	}
+}
+
+// Fatal输出日志(含堆栈)并写完缓冲区后以状态1退出, 在子进程中运行
+func TestFatal(t *testing.T) {
+	if filename := os.Getenv("TEST_FATAL_LOG_FILE"); filename != "" {
+		writer, err := NewFileLogWriter("file", INFO, filename, false, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger := NewLogger()
+		logger.SetLogWriter("file", writer.SetFormat("%L %M"))
+		logger.Fatal("fatal %s", "bye")
+		t.Fatal("Fatal returned")
+	}
+
+	filename := filepath.Join(tempDir(t), "fatal.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatal$")
+	cmd.Env = append(os.Environ(), "TEST_FATAL_LOG_FILE="+filename)
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("expect exit status 1, got %v", err)
+	}
+
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(contents), "FATL fatal bye") || !strings.Contains(string(contents), "goroutine ") {
+		t.Errorf("log file: %q", contents)
+	}
+}
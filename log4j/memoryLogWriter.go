@@ -0,0 +1,256 @@
+package log4j
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMemorySize = 1000
+
+// 在内存中按日志的tag分别保留最近size条日志, 供Query查询(如/debug/logs页面)
+type MemoryLogWriter struct {
+	level   Level
+	private bool
+	size    int
+
+	lock  sync.RWMutex
+	rings map[string]*logRing
+}
+
+// 环形缓冲区, 写满后覆盖最旧的日志
+type logRing struct {
+	recs []*LogRecord
+	next int
+}
+
+func (r *logRing) add(rec *LogRecord, size int) {
+	if len(r.recs) < size {
+		r.recs = append(r.recs, rec)
+		return
+	}
+	r.recs[r.next] = rec
+	r.next = (r.next + 1) % size
+}
+
+// 查询条件, 零值表示不限制
+type MemoryQuery struct {
+	Level    Level          // 最低级别
+	Since    time.Time      // 不早于
+	Until    time.Time      // 早于
+	Contains string         // Message包含的字符串
+	Regexp   *regexp.Regexp // Message匹配的正则
+	Tag      string         // 日志的tag
+	Limit    int            // 最多返回最新的Limit条
+}
+
+func (q *MemoryQuery) match(rec *LogRecord) bool {
+	switch {
+	case rec.Level < q.Level:
+		return false
+	case !q.Since.IsZero() && rec.Created.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !rec.Created.Before(q.Until):
+		return false
+	case q.Contains != "" && !strings.Contains(rec.Message, q.Contains):
+		return false
+	case q.Regexp != nil && !q.Regexp.MatchString(rec.Message):
+		return false
+	}
+	return true
+}
+
+// size为每个tag保留的日志条数
+func NewMemoryLogWriter(level Level, size int) *MemoryLogWriter {
+	if size <= 0 {
+		size = defaultMemorySize
+	}
+	return &MemoryLogWriter{
+		level: level,
+		size:  size,
+		rings: map[string]*logRing{},
+	}
+}
+
+func (w *MemoryLogWriter) LogWrite(rec *LogRecord) {
+	if rec == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	ring, ok := w.rings[rec.Tag]
+	if !ok {
+		ring = &logRing{}
+		w.rings[rec.Tag] = ring
+	}
+	ring.add(rec, w.size)
+}
+
+// 按时间先后返回符合条件的日志
+func (w *MemoryLogWriter) Query(query MemoryQuery) []*LogRecord {
+	w.lock.RLock()
+	result := make([]*LogRecord, 0)
+	for tag, ring := range w.rings {
+		if query.Tag != "" && query.Tag != tag {
+			continue
+		}
+		for _, rec := range ring.recs {
+			if query.match(rec) {
+				result = append(result, rec)
+			}
+		}
+	}
+	w.lock.RUnlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[len(result)-query.Limit:]
+	}
+	return result
+}
+
+// 已保留日志的tag
+func (w *MemoryLogWriter) Tags() []string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	tags := make([]string, 0, len(w.rings))
+	for tag := range w.rings {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (w *MemoryLogWriter) Close() {
+}
+
+func (w *MemoryLogWriter) IsPrivate() bool {
+	return w.private
+}
+
+func (w *MemoryLogWriter) GetLevel() Level {
+	return w.level
+}
+
+func (w *MemoryLogWriter) SetPrivate(private bool) *MemoryLogWriter {
+	w.private = private
+	return w
+}
+
+// 当前配置中所有的MemoryLogWriter, key为<filter>的tag
+func (p *Logger) MemoryLogWriters() map[string]*MemoryLogWriter {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	writers := map[string]*MemoryLogWriter{}
+	for tag, logWriter := range p.logWriterMap {
+		if memory, ok := unwrapLogWriter(logWriter).(*MemoryLogWriter); ok {
+			writers[tag] = memory
+		}
+	}
+	return writers
+}
+
+// 查询MemoryLogWriter中的日志, 每次请求时查找当前配置中的MemoryLogWriter(Reload后仍有效).
+// 参数: writer(<filter>的tag, 默认全部), level, since/until(RFC3339时间或如10m的时长, 表示多久之前),
+// q(包含的字符串), re(正则), tag(日志的tag), limit(默认200), format(json或日志格式, 默认defaultFormat)
+func (p *Logger) MemoryHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		params := request.URL.Query()
+		query := MemoryQuery{Contains: params.Get("q"), Tag: params.Get("tag"), Limit: 200}
+
+		errMsg, ok := "", false
+		if value := params.Get("level"); value != "" {
+			if query.Level, ok = parseLevel(strings.ToUpper(value)); !ok {
+				errMsg = "invalid level: " + value
+			}
+		}
+		if value := params.Get("since"); value != "" {
+			if query.Since, ok = parseQueryTime(value); !ok {
+				errMsg = "invalid since: " + value
+			}
+		}
+		if value := params.Get("until"); value != "" {
+			if query.Until, ok = parseQueryTime(value); !ok {
+				errMsg = "invalid until: " + value
+			}
+		}
+		if value := params.Get("re"); value != "" {
+			re, err := regexp.Compile(value)
+			if err != nil {
+				errMsg = "invalid re: " + err.Error()
+			}
+			query.Regexp = re
+		}
+		if value := params.Get("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				errMsg = "invalid limit: " + value
+			}
+			query.Limit = limit
+		}
+		if errMsg != "" {
+			http.Error(writer, errMsg, http.StatusBadRequest)
+			return
+		}
+
+		format := params.Get("format")
+		if format == "" {
+			format = defaultFormat
+		}
+
+		records := make([]*LogRecord, 0)
+		for tag, memory := range p.MemoryLogWriters() {
+			if name := params.Get("writer"); name == "" || name == tag {
+				records = append(records, memory.Query(query)...)
+			}
+		}
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].Created.Before(records[j].Created)
+		})
+		if query.Limit > 0 && len(records) > query.Limit {
+			records = records[len(records)-query.Limit:]
+		}
+
+		// format来自请求, 不缓存解析结果
+		out := &bytes.Buffer{}
+		if format == JsonFormat {
+			for _, rec := range records {
+				formatJsonLogRecord(out, rec)
+			}
+		} else {
+			pieces := parseFormat(format)
+			for _, rec := range records {
+				formatPieces(out, pieces, rec, "")
+			}
+		}
+
+		if format == JsonFormat {
+			writer.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		_, _ = writer.Write(out.Bytes())
+	})
+}
+
+// RFC3339时间, 或时长(表示当前时间之前多久)
+func parseQueryTime(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,115 @@
+package log4j
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryQuery(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	w := NewMemoryLogWriter(TRACE, 3)
+	for i, rec := range []LogRecord{
+		{Level: DEBUG, Tag: "a", Message: "a-0 start"},
+		{Level: INFO, Tag: "b", Message: "b-1 order 17"},
+		{Level: WARNING, Tag: "a", Message: "a-2 order 18"},
+		{Level: ERROR, Tag: "a", Message: "a-3 fail"},
+		{Level: INFO, Tag: "a", Message: "a-4 order 19"},
+	} {
+		rec := rec
+		rec.Created = base.Add(time.Duration(i) * time.Minute)
+		w.LogWrite(&rec)
+	}
+	w.LogWrite(nil) // 空行不保留
+
+	cases := []struct {
+		desc  string
+		query MemoryQuery
+		want  string
+	}{
+		{"all, a-0 overwritten", MemoryQuery{}, "b-1 a-2 a-3 a-4"},
+		{"level", MemoryQuery{Level: WARNING}, "a-2 a-3"},
+		{"since", MemoryQuery{Since: base.Add(2 * time.Minute)}, "a-2 a-3 a-4"},
+		{"until exclusive", MemoryQuery{Until: base.Add(2 * time.Minute)}, "b-1"},
+		{"contains", MemoryQuery{Contains: "order"}, "b-1 a-2 a-4"},
+		{"regexp", MemoryQuery{Regexp: regexp.MustCompile(`order 1[89]$`)}, "a-2 a-4"},
+		{"tag", MemoryQuery{Tag: "b"}, "b-1"},
+		{"limit keeps newest", MemoryQuery{Limit: 2}, "a-3 a-4"},
+		{"combined", MemoryQuery{Tag: "a", Level: INFO, Contains: "order", Limit: 1}, "a-4"},
+	}
+	for _, c := range cases {
+		got := make([]string, 0)
+		for _, rec := range w.Query(c.query) {
+			got = append(got, strings.Fields(rec.Message)[0])
+		}
+		if strings.Join(got, " ") != c.want {
+			t.Errorf("%s: got %v, want %s", c.desc, got, c.want)
+		}
+	}
+
+	if tags := w.Tags(); strings.Join(tags, ",") != "a,b" {
+		t.Errorf("Tags() = %v", tags)
+	}
+}
+
+func TestMemoryHandler(t *testing.T) {
+	logger := NewLogger()
+	defer logger.Close()
+	if err := logger.LoadConfigurationFromReader(strings.NewReader(memoryConfig("m1", "DEBUG", "m2", "WARNING"))); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("debug 1")
+	logger.Warn("warn 2")
+	logger.Error("error 3")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		logger.MemoryHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/logs?"+query, nil))
+		return recorder
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"format=%25M", []string{"debug 1", "warn 2", "warn 2", "error 3", "error 3"}},
+		{"format=%25M&writer=m2", []string{"warn 2", "error 3"}},
+		{"format=%25M&writer=m1&level=warning&limit=1", []string{"error 3"}},
+		{"format=%25M&writer=m1&q=2", []string{"warn 2"}},
+		{"format=%25M&writer=m1&re=^(debug|error)", []string{"debug 1", "error 3"}},
+		{"format=%25M&writer=m1&since=1h", []string{"debug 1", "warn 2", "error 3"}},
+		{"format=%25M&writer=m1&until=2000-01-01T00:00:00Z", []string{}},
+	}
+	for _, c := range cases {
+		recorder := get(c.query)
+		if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+			t.Errorf("%s: code %d, content type %s", c.query, recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+		got := strings.TrimSuffix(recorder.Body.String(), "\n")
+		if got != strings.Join(c.want, "\n") {
+			t.Errorf("%s: got %q, want %q", c.query, got, c.want)
+		}
+	}
+
+	recorder := get("format=json&writer=m2")
+	if recorder.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("json content type %s", recorder.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSuffix(recorder.Body.String(), "\n"), "\n")
+	for i, want := range []string{"WARNING", "ERROR"} {
+		rec := struct{ Level, Message string }{}
+		if i >= len(lines) || json.Unmarshal([]byte(lines[i]), &rec) != nil || rec.Level != want {
+			t.Errorf("json line %d: %q", i, lines)
+		}
+	}
+
+	for _, query := range []string{"level=LOUD", "since=yesterday", "until=x", "re=(", "limit=-1", "limit=x"} {
+		if recorder := get(query); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: code %d, want 400", query, recorder.Code)
+		}
+	}
+}
@@ -5,6 +5,9 @@
 package log4j
 
 import (
+	"encoding/xml"
+	"net/http"
+	"regexp"
 	"time"
 )
 
@@ -17,25 +20,139 @@ type LogProperty struct {
 	Daily    bool
 	KeepDay  int64
 	Private  bool
+
+	RotatePeriod  string // 按时间切割的周期: RotateMinute、RotateHour、RotateDay、RotateWeek
+	RotatePattern string // 切割文件名的时间后缀格式(Go time layout), 如"2006010215"
+	Compress      string // 切割后的文件压缩方式: CompressGzip
+
+	MaxBackups   int   // 最多保留的切割文件数
+	MaxTotalSize int64 // 切割文件与正在写的文件总大小上限
+
+	// 缓冲区满时的处理策略, OverflowTimeout时阻塞OverflowTimeout后丢弃; 都未配置时http类型为OverflowDropOldest, 其他类型为OverflowBlock
+	Overflow        OverflowPolicy
+	OverflowTimeout time.Duration
+
+	// console: 彩色输出模式ColorAuto、ColorAlways、ColorNever, ColorLine时整行彩色; HasStderrLevel时级别不低于StderrLevel的输出到stderr
+	Color          string
+	ColorLine      bool
+	HasStderrLevel bool
+	StderrLevel    Level
+
+	// syslog: 网络(udp、tcp、unix、unixgram, 为空时连接本机syslog)及地址
+	Network     string
+	Address     string
+	Facility    int
+	AppName     string
+	SyslogRFC   string        // SyslogRFC5424 或 SyslogRFC3164
+	SeverityMap map[Level]int // 日志级别对应的syslog severity, 未设置的使用默认值
+
+	// socket: 断线期间缓存在内存(SpillMemory, 最多BufferSize条)或文件(SpillFile, 最大SpillMaxSize)
+	Spill        string
+	BufferSize   int
+	SpillFile    string
+	SpillMaxSize int64
+	MaxBackoff   time.Duration
+
+	// http: 攒批发送到URL, 满BatchSize条或等待BatchLatency后发送, 失败时最多重试MaxRetries次(退避上限MaxBackoff)
+	URL          string
+	Header       http.Header
+	Gzip         bool
+	BatchSize    int
+	BatchLatency time.Duration
+	QueueSize    int
+	MaxRetries   int
+	Timeout      time.Duration
+
+	// memory: 每个日志tag保留的日志条数
+	MemorySize int
+
+	// 各类型都支持: SuppressWindow内相同的日志只输出一条; 每个调用位置每秒输出前SampleFirst条, 之后每SampleThereafter条输出一条
+	SuppressWindow   time.Duration
+	SampleFirst      int
+	SampleThereafter int
+
+	// 私有LogWriter的路由: 还接收tag匹配Tags(glob)的日志; RouteUntagged时还接收写入共享LogWriter且级别不低于UntaggedLevel的日志;
+	// Mirror时写入本LogWriter的日志同时写入共享LogWriter
+	Tags          []string
+	RouteUntagged bool
+	UntaggedLevel Level
+	Mirror        bool
+
+	// 各类型都支持的内容过滤: Message匹配Include且不匹配Exclude, Source以IncludeSource之一开头且不以ExcludeSource之一开头,
+	// HasMaxLevel时级别不高于MaxLevel
+	Include       *regexp.Regexp
+	Exclude       *regexp.Regexp
+	IncludeSource []string
+	ExcludeSource []string
+	HasMaxLevel   bool
+	MaxLevel      Level
+}
+
+// 只配置了OverflowTimeout时使用OverflowTimeout策略
+func (p *LogProperty) overflowPolicy() (OverflowPolicy, time.Duration) {
+	if p.Overflow == OverflowBlock && p.OverflowTimeout > 0 {
+		return OverflowTimeout, p.OverflowTimeout
+	}
+	if p.Overflow == OverflowTimeout && p.OverflowTimeout <= 0 {
+		return OverflowTimeout, 100 * time.Millisecond
+	}
+	return p.Overflow, p.OverflowTimeout
 }
 
-type logRecord struct {
+// 一条日志, 会被同时传给多个LogWriter, LogWriter不应修改它
+type LogRecord struct {
 	Level   Level     // The log level
 	Created time.Time // The time at which the log message was created (nanoseconds)
 	Source  string    // The message source
+	File    string    // 调用位置, 文件路径:行号
 	Message string    // The log message
+	Stack   string    // 堆栈信息, ErrorStack等才有
+	Tag     string    // 调用时指定的tag
+	Fields  []Field   // 结构化字段, 由With添加
+	Context []Field   // 从context.Context取出的值, 如traceId
+
+	Goroutine int64 // goroutine id, 有格式使用%G时才获取
 }
 
-type logWriter interface {
-	LogWrite(rec *logRecord)
+// 结构化字段, 格式化时以 k=v 输出
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// 日志输出端; LogWrite被并发调用, 不应长时间阻塞; Close需写完已接收的日志后返回
+type LogWriter interface {
+	LogWrite(rec *LogRecord)
 	Close()
 	IsPrivate() bool
 	GetLevel() Level
 }
 
+// 包装了其他LogWriter的LogWriter(如过滤、抑制重复日志)实现此接口, 以便找到被包装的LogWriter
+type logWriterWrapper interface {
+	Unwrap() LogWriter
+}
+
+// 格式含%G的LogWriter, 记录日志时在调用方goroutine中获取goroutine id
+type goroutineLogWriter interface {
+	needGoroutine() bool
+}
+
+func unwrapLogWriter(logWriter LogWriter) LogWriter {
+	for {
+		wrapper, ok := logWriter.(logWriterWrapper)
+		if !ok {
+			return logWriter
+		}
+		logWriter = wrapper.Unwrap()
+	}
+}
+
 type xmlProperty struct {
 	Name  string `xml:"name,attr"`
 	Value string `xml:",chardata"`
+
+	offset int64 // 在配置文件中的位置, 用于错误提示行号
 }
 
 type xmlFilter struct {
@@ -44,8 +161,56 @@ type xmlFilter struct {
 	Level    string        `xml:"level"`
 	Type     string        `xml:"type"`
 	Property []xmlProperty `xml:"property"`
+
+	offset int64
+}
+
+func (p *xmlProperty) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.offset = d.InputOffset()
+	type plain xmlProperty
+	return d.DecodeElement((*plain)(p), &start)
+}
+
+func (p *xmlFilter) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.offset = d.InputOffset()
+	type plain xmlFilter
+	return d.DecodeElement((*plain)(p), &start)
+}
+
+// 按包/函数路径设置的级别, 如<logger name="github.com/acme/order" level="DEBUG"/>, name为root时匹配其他调用方.
+// 只用于过滤: 低于该级别的日志不输出, 不低于的仍需达到各LogWriter的级别; 不会降低LogWriter的级别,
+// 要输出某个包的DEBUG日志, LogWriter的<level>也须为DEBUG或更低, 再用root限制其他调用方
+type xmlLogger struct {
+	Name  string `xml:"name,attr" json:"name"`
+	Level string `xml:"level,attr" json:"level"`
+
+	offset int64
+}
+
+func (p *xmlLogger) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.offset = d.InputOffset()
+	type plain xmlLogger
+	return d.DecodeElement((*plain)(p), &start)
 }
 
 type xmlLoggerConfig struct {
 	Filter []xmlFilter `xml:"filter"`
+	Logger []xmlLogger `xml:"logger"`
+}
+
+// json格式配置, 与xml的<filter>语义相同:
+//
+//	{"filters": [{"enabled": true, "tag": "app", "type": "file", "level": "INFO", "properties": {"filename": "/log/app.log"}}],
+//	 "loggers": [{"name": "github.com/acme/order", "level": "DEBUG"}]}
+type jsonLoggerConfig struct {
+	Filters []jsonFilter `json:"filters"`
+	Loggers []xmlLogger  `json:"loggers"`
+}
+
+type jsonFilter struct {
+	Enabled    *bool                  `json:"enabled"`
+	Tag        string                 `json:"tag"`
+	Level      string                 `json:"level"`
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
 }
@@ -0,0 +1,77 @@
+package log4j
+
+import (
+	"path"
+)
+
+// 私有LogWriter的额外路由规则, 由property tags、untaggedLevel、mirror配置
+type routeRule struct {
+	patterns      []string // 还接收tag匹配这些glob(如order-*)的日志
+	untagged      bool     // 还接收写入共享LogWriter的日志中, 级别不低于untaggedLevel的
+	untaggedLevel Level
+	mirror        bool // 写入本LogWriter的日志同时写入共享LogWriter
+}
+
+func newRouteRule(prop *LogProperty) *routeRule {
+	if prop == nil || (len(prop.Tags) == 0 && !prop.RouteUntagged && !prop.Mirror) {
+		return nil
+	}
+	return &routeRule{
+		patterns:      prop.Tags,
+		untagged:      prop.RouteUntagged,
+		untaggedLevel: prop.UntaggedLevel,
+		mirror:        prop.Mirror,
+	}
+}
+
+func (r *routeRule) matchTag(tag string) bool {
+	if r == nil {
+		return false
+	}
+	for _, pattern := range r.patterns {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func buildRouteRules(filters []*filterConfig) map[string]*routeRule {
+	routes := map[string]*routeRule{}
+	for _, filter := range filters {
+		if rule := newRouteRule(filter.prop); rule != nil {
+			routes[filter.tag] = rule
+		}
+	}
+	return routes
+}
+
+// 日志的tag: 调用时指定的tag及Entry.Tags指定的tag, 去重且不含空字符串
+func routeTags(tag string, entry *Entry) []string {
+	if entry == nil || len(entry.tags) == 0 {
+		if tag == "" {
+			return nil
+		}
+		return []string{tag}
+	}
+
+	tags := make([]string, 0, len(entry.tags)+1)
+	for _, t := range append([]string{tag}, entry.tags...) {
+		if t == "" {
+			continue
+		}
+		if !containsString(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package log4j
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	cases := []struct {
+		desc string
+		log  func(logger *Logger)
+		want string // 收到日志的LogWriter, 按tag排序
+	}{
+		{"untagged", func(l *Logger) { l.Info("m") }, "shared"},
+		{"untagged at untaggedLevel", func(l *Logger) { l.Warn("m") }, "audit,shared"},
+		{"untagged below shared level", func(l *Logger) { l.Trace("m") }, ""},
+		{"private tag", func(l *Logger) { l.InfoTag("order", "m") }, "order"},
+		{"glob", func(l *Logger) { l.InfoTag("order-eu", "m") }, "order"},
+		{"glob no match", func(l *Logger) { l.InfoTag("orderx", "m") }, "shared"},
+		{"unknown tag at untaggedLevel", func(l *Logger) { l.ErrorTag("unknown", "m") }, "audit,shared"},
+		{"private tag not untagged", func(l *Logger) { l.ErrorTag("order", "m") }, "order"},
+		{"mirror", func(l *Logger) { l.InfoTag("pay", "m") }, "pay,shared"},
+		{"private level", func(l *Logger) { l.DebugTag("ops", "m") }, ""},
+		{"multiple private tags", func(l *Logger) { l.Tags("order", "ops").Info("m") }, "ops,order"},
+		{"tag and entry tags", func(l *Logger) { l.Tags("ops").InfoTag("order", "m") }, "ops,order"},
+		{"private and unknown tags", func(l *Logger) { l.Tags("order", "unknown").Info("m") }, "order,shared"},
+		{"written once", func(l *Logger) { l.Tags("order", "order-eu", "order").Info("m") }, "order"},
+		{"mirror and private", func(l *Logger) { l.Tags("pay", "order").Warn("m") }, "audit,order,pay,shared"},
+	}
+
+	for _, c := range cases {
+		writers := map[string]*testLogWriter{
+			"shared": {level: DEBUG},
+			"order":  {level: DEBUG, private: true},
+			"ops":    {level: INFO, private: true},
+			"pay":    {level: DEBUG, private: true},
+			"audit":  {level: DEBUG, private: true},
+		}
+		logger := NewLogger()
+		for tag, w := range writers {
+			logger.SetLogWriter(tag, w)
+		}
+		logger.routes = buildRouteRules([]*filterConfig{
+			{tag: "order", prop: &LogProperty{Tags: []string{"order-*"}}},
+			{tag: "pay", prop: &LogProperty{Mirror: true}},
+			{tag: "audit", prop: &LogProperty{RouteUntagged: true, UntaggedLevel: WARNING}},
+			{tag: "ops", prop: &LogProperty{}},
+		})
+
+		c.log(logger)
+
+		got := make([]string, 0)
+		for tag, w := range writers {
+			if n := len(w.records()); n > 1 {
+				t.Errorf("%s: %s got %d records", c.desc, tag, n)
+			} else if n == 1 {
+				got = append(got, tag)
+			}
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != c.want {
+			t.Errorf("%s: written to %v, want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestRouteTags(t *testing.T) {
+	cases := []struct {
+		tag   string
+		entry *Entry
+		want  string
+	}{
+		{"", nil, ""},
+		{"a", nil, "a"},
+		{"", &Entry{tags: []string{"b", "", "c"}}, "b,c"},
+		{"a", &Entry{tags: []string{"b", "a", "b"}}, "a,b"},
+	}
+	for _, c := range cases {
+		if got := strings.Join(routeTags(c.tag, c.entry), ","); got != c.want {
+			t.Errorf("routeTags(%q, %v) = %s, want %s", c.tag, c.entry, got, c.want)
+		}
+	}
+
+	if buildRouteRules([]*filterConfig{{tag: "plain", prop: &LogProperty{Private: true}}})["plain"] != nil {
+		t.Error("expect no route rule without tags, untaggedLevel or mirror")
+	}
+}
@@ -0,0 +1,439 @@
+package log4j
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// 断线期间日志的缓存方式
+const (
+	SpillMemory = "memory"
+	SpillFile   = "file"
+)
+
+const (
+	socketDialTimeout  = time.Second * 5
+	socketWriteTimeout = time.Second * 5
+	socketRetryTick    = time.Millisecond * 200
+	socketMinBackoff   = time.Millisecond * 500
+
+	defaultSocketMaxBackoff   = time.Second * 30
+	defaultSocketBufferSize   = 10000
+	defaultSocketSpillMaxSize = 100 * 1024 * 1024
+)
+
+// 通过tcp或udp发送格式化后的日志, 断线时按退避间隔重连, 期间的日志缓存在内存或文件中, 重连后按顺序重发
+type SocketLogWriter struct {
+	*logChannel
+
+	level   Level
+	tag     string
+	closeCh chan bool
+	logFormat
+	private bool
+
+	network    string
+	address    string
+	conn       net.Conn
+	backoff    time.Duration
+	maxBackoff time.Duration
+	nextDial   time.Time
+	failed     bool // 已输出断线提示
+
+	bufferSize int
+	spill      socketSpill      // 第一条日志或SetSpillFile时创建
+	spillCh    chan socketSpill // SetSpillFile打开的文件缓存, 交给run()使用
+}
+
+// 断线期间的日志缓存, 重连后按写入顺序重发
+type socketSpill interface {
+	push(msg []byte) bool // 缓存已满时返回false, 该条日志被丢弃
+	replay(conn net.Conn) (int, error)
+	len() int
+	close()
+}
+
+func NewSocketLogWriter(tag string, level Level, network, address string) *SocketLogWriter {
+	writer := &SocketLogWriter{
+		level:      level,
+		tag:        tag,
+		logChannel: newLogChannel(LogBufferLength),
+		closeCh:    make(chan bool),
+		logFormat:  logFormat{format: "[%D %T] [%L] (%S) %M"},
+		network:    network,
+		address:    address,
+		backoff:    socketMinBackoff,
+		maxBackoff: defaultSocketMaxBackoff,
+		bufferSize: defaultSocketBufferSize,
+		spillCh:    make(chan socketSpill, 1),
+	}
+	go writer.run()
+
+	printlnIO(os.Stdout, "INFO", "socketLogWriter[%s], create success, network:%s, address:%s", tag, network, address)
+	return writer
+}
+
+func (w *SocketLogWriter) LogWrite(rec *LogRecord) {
+	w.logChannel.put(rec)
+}
+
+func (w *SocketLogWriter) Close() {
+	w.logChannel.close()
+	<-w.closeCh // 等待run()将日志全部发送后return
+	printlnIO(os.Stdout, "INFO", "socketLogWriter[%s] is closed", w.tag)
+}
+
+// 收到第一条日志或SetSpillFile的文件缓存前不连接, 此前的Set方法不会与run()并发
+func (w *SocketLogWriter) run() {
+	ticker := time.NewTicker(socketRetryTick)
+	defer func() {
+		ticker.Stop()
+		if w.spill != nil {
+			if n := w.spill.len(); n > 0 {
+				printlnIO(os.Stderr, "ERROR", "socketLogWriter[%s] closed while disconnected, %d records not sent", w.tag, n)
+			}
+			w.spill.close()
+		}
+		w.closeConn()
+		w.closeCh <- true
+	}()
+
+	for {
+		select {
+		case rec, isAlive := <-w.logChannel.ch:
+			if !isAlive {
+				select {
+				case spill := <-w.spillCh:
+					w.useSpill(spill)
+				default:
+				}
+				if w.conn == nil && w.spill != nil && w.spill.len() > 0 {
+					w.nextDial = time.Time{} // 关闭前再尝试一次
+					w.connect()
+				}
+				return
+			}
+
+			if w.spill == nil {
+				w.spill = &memorySpill{max: w.bufferSize}
+			}
+			w.send(rec)
+			if notice := w.logChannel.droppedNotice(); notice != nil {
+				w.send(notice)
+			}
+
+		case spill := <-w.spillCh:
+			w.useSpill(spill)
+
+		case <-ticker.C:
+			if w.conn == nil && w.spill != nil {
+				w.connect()
+			}
+		}
+	}
+}
+
+// 换用文件缓存, 内存中未重发的日志追加在文件中残留的日志之后; 有残留日志时立即重发
+func (w *SocketLogWriter) useSpill(spill socketSpill) {
+	if memory, ok := w.spill.(*memorySpill); ok {
+		for _, msg := range memory.msgs {
+			if !spill.push(msg) {
+				w.logChannel.drop()
+			}
+		}
+	}
+	if w.spill != nil {
+		w.spill.close()
+	}
+	w.spill = spill
+
+	if w.spill.len() == 0 {
+		return
+	}
+	if w.conn == nil {
+		w.connect()
+	} else if _, err := w.spill.replay(w.conn); err != nil {
+		w.disconnect(err)
+	}
+}
+
+func (w *SocketLogWriter) send(rec *LogRecord) {
+	msg := &bytes.Buffer{}
+	if _, err := fPrintFormatLog(msg, w.format, rec); err != nil || msg.Len() == 0 {
+		return
+	}
+
+	// 未连接或还有未重发的日志时先缓存, 保证顺序
+	if (w.conn == nil && !w.connect()) || w.spill.len() > 0 {
+		w.push(msg.Bytes())
+		return
+	}
+
+	_ = w.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	if _, err := w.conn.Write(msg.Bytes()); err != nil {
+		w.disconnect(err)
+		w.push(msg.Bytes())
+	}
+}
+
+func (w *SocketLogWriter) push(msg []byte) {
+	if !w.spill.push(msg) {
+		w.logChannel.drop()
+	}
+}
+
+// 连接成功后先重发缓存的日志; 失败时按退避间隔等待下次重连
+func (w *SocketLogWriter) connect() bool {
+	if time.Now().Before(w.nextDial) {
+		return false
+	}
+
+	conn, err := net.DialTimeout(w.network, w.address, socketDialTimeout)
+	if err != nil {
+		w.disconnect(err)
+		return false
+	}
+	w.conn = conn
+
+	pending := w.spill.len()
+	replayed, err := w.spill.replay(conn)
+	if err != nil {
+		w.disconnect(err)
+		return false
+	}
+
+	if w.failed {
+		printlnIO(os.Stdout, "INFO", "socketLogWriter[%s] reconnected, replayed %d of %d records", w.tag, replayed, pending)
+	}
+	w.failed, w.backoff, w.nextDial = false, socketMinBackoff, time.Time{}
+	return true
+}
+
+func (w *SocketLogWriter) disconnect(err error) {
+	w.closeConn()
+
+	if !w.failed {
+		w.failed = true
+		printlnIO(os.Stderr, "ERROR", "socketLogWriter[%s] %s:%s disconnected, err:%s", w.tag, w.network, w.address, err.Error())
+	}
+
+	w.nextDial = time.Now().Add(w.backoff)
+	if w.backoff *= 2; w.backoff > w.maxBackoff {
+		w.backoff = w.maxBackoff
+	}
+}
+
+func (w *SocketLogWriter) closeConn() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+func (w *SocketLogWriter) IsPrivate() bool {
+	return w.private
+}
+
+func (w *SocketLogWriter) GetLevel() Level {
+	return w.level
+}
+
+func (w *SocketLogWriter) SetFormat(format string) *SocketLogWriter {
+	w.setFormat(format)
+	return w
+}
+
+func (w *SocketLogWriter) SetPrivate(private bool) *SocketLogWriter {
+	w.private = private
+	return w
+}
+
+// 重连的最大退避间隔, 从500ms开始翻倍
+func (w *SocketLogWriter) SetMaxBackoff(maxBackoff time.Duration) *SocketLogWriter {
+	if maxBackoff > 0 {
+		w.maxBackoff = maxBackoff
+	}
+	return w
+}
+
+// 断线期间在内存中最多缓存的日志条数, 超出时丢弃新日志
+func (w *SocketLogWriter) SetBufferSize(bufferSize int) *SocketLogWriter {
+	if bufferSize > 0 {
+		w.bufferSize = bufferSize
+	}
+	return w
+}
+
+// 断线期间的日志写入文件而不是内存, 超过maxSize时丢弃新日志; 打开失败时仍缓存在内存中
+// 文件中有上次进程残留的日志时立即连接重发, 不必等到写日志; 其他Set方法应在此之前调用
+func (w *SocketLogWriter) SetSpillFile(filename string, maxSize int64) *SocketLogWriter {
+	if maxSize <= 0 {
+		maxSize = defaultSocketSpillMaxSize
+	}
+	spill, err := newFileSpill(filename, maxSize)
+	if err != nil {
+		printlnIO(os.Stderr, "ERROR", "socketLogWriter[%s] open spill file:%s fail, use memory, err:%s", w.tag, filename, err.Error())
+		return w
+	}
+	w.spillCh <- spill
+	return w
+}
+
+type memorySpill struct {
+	msgs [][]byte
+	max  int
+}
+
+func (s *memorySpill) push(msg []byte) bool {
+	if len(s.msgs) >= s.max {
+		return false
+	}
+	s.msgs = append(s.msgs, append([]byte(nil), msg...))
+	return true
+}
+
+func (s *memorySpill) replay(conn net.Conn) (int, error) {
+	for i, msg := range s.msgs {
+		_ = conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+		if _, err := conn.Write(msg); err != nil {
+			s.msgs = s.msgs[i:]
+			return i, err
+		}
+	}
+	replayed := len(s.msgs)
+	s.msgs = nil
+	return replayed, nil
+}
+
+func (s *memorySpill) len() int {
+	return len(s.msgs)
+}
+
+func (s *memorySpill) close() {
+	s.msgs = nil
+}
+
+// 每条日志以4字节长度开头追加到文件, 重发完成后清空文件
+// 重发进度保存在<spillFile>.offset中, 进程在重发途中退出时, 重启后不会重复发送已重发的日志
+// (写入连接成功但对端未处理的一条除外)
+type fileSpill struct {
+	file       *os.File
+	offsetFile *os.File
+	maxSize    int64
+	size       int64 // 文件大小
+	offset     int64 // 已重发到的位置
+	count      int   // 未重发的日志条数
+}
+
+func newFileSpill(filename string, maxSize int64) (*fileSpill, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, err
+	}
+	offsetFile, err := os.OpenFile(filename+".offset", os.O_RDWR|os.O_CREATE, 0660)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	s := &fileSpill{file: file, offsetFile: offsetFile, maxSize: maxSize}
+
+	saved := int64(0)
+	head := make([]byte, 8)
+	if _, err := offsetFile.ReadAt(head, 0); err == nil {
+		saved = int64(binary.BigEndian.Uint64(head))
+	}
+
+	// 统计上次进程退出时残留的日志, 跳过已重发的
+	for {
+		if s.size == saved {
+			s.offset, s.count = saved, 0
+		}
+		length, err := s.readLength(s.size)
+		if err != nil || s.size+4+length > s.fileSize() {
+			break
+		}
+		s.size += 4 + length
+		s.count++
+	}
+	return s, file.Truncate(s.size) // 丢弃写了一半的日志
+}
+
+func (s *fileSpill) saveOffset() error {
+	head := make([]byte, 8)
+	binary.BigEndian.PutUint64(head, uint64(s.offset))
+	_, err := s.offsetFile.WriteAt(head, 0)
+	return err
+}
+
+func (s *fileSpill) fileSize() int64 {
+	if info, err := s.file.Stat(); err == nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (s *fileSpill) readLength(offset int64) (int64, error) {
+	head := make([]byte, 4)
+	if _, err := s.file.ReadAt(head, offset); err != nil {
+		return 0, err
+	}
+	return int64(head[0])<<24 | int64(head[1])<<16 | int64(head[2])<<8 | int64(head[3]), nil
+}
+
+func (s *fileSpill) push(msg []byte) bool {
+	if s.size+4+int64(len(msg)) > s.maxSize {
+		return false
+	}
+	length := len(msg)
+	record := append([]byte{byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)}, msg...)
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		return false
+	}
+	s.size += int64(len(record))
+	s.count++
+	return true
+}
+
+func (s *fileSpill) replay(conn net.Conn) (int, error) {
+	replayed := 0
+	for s.offset < s.size {
+		length, err := s.readLength(s.offset)
+		if err != nil {
+			return replayed, err
+		}
+		msg := make([]byte, length)
+		if _, err := s.file.ReadAt(msg, s.offset+4); err != nil && err != io.EOF {
+			return replayed, err
+		}
+
+		_ = conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+		if _, err := conn.Write(msg); err != nil {
+			return replayed, err
+		}
+		s.offset += 4 + length
+		s.count--
+		replayed++
+		if err := s.saveOffset(); err != nil {
+			return replayed, err
+		}
+	}
+
+	s.size, s.offset, s.count = 0, 0, 0
+	if err := s.file.Truncate(0); err != nil {
+		return replayed, err
+	}
+	return replayed, s.saveOffset()
+}
+
+func (s *fileSpill) len() int {
+	return s.count
+}
+
+func (s *fileSpill) close() {
+	_ = s.file.Close()
+	_ = s.offsetFile.Close()
+}
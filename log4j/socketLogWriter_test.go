@@ -0,0 +1,211 @@
+package log4j
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 本地tcp服务端, 按行接收日志
+type socketServer struct {
+	ln    net.Listener
+	lines chan string
+
+	lock  sync.Mutex
+	conns []net.Conn
+}
+
+func startSocketServer(t *testing.T, address string) *socketServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socketServer{ln: ln, lines: make(chan string, 100)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.conns = append(s.conns, conn)
+			s.lock.Unlock()
+			go func() {
+				for scanner := bufio.NewScanner(conn); scanner.Scan(); {
+					s.lines <- strings.TrimSpace(scanner.Text())
+				}
+			}()
+		}
+	}()
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *socketServer) close() {
+	_ = s.ln.Close()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *socketServer) expect(t *testing.T, want ...string) {
+	t.Helper()
+	for _, line := range want {
+		select {
+		case got := <-s.lines:
+			if got != line {
+				t.Fatalf("got %q, want %q", got, line)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for %q", line)
+		}
+	}
+}
+
+// 没有服务端监听的地址
+func unusedAddress(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	_ = ln.Close()
+	return address
+}
+
+func TestSocketReconnect(t *testing.T) {
+	server := startSocketServer(t, "127.0.0.1:0")
+	address := server.ln.Addr().String()
+	w := NewSocketLogWriter("sock", TRACE, "tcp", address).SetFormat("%M")
+	defer w.Close()
+
+	w.LogWrite(testRecord("1"))
+	w.LogWrite(testRecord("2"))
+	server.expect(t, "1", "2")
+
+	// 对端关闭后第一次写入仍会成功(丢失), 此后写失败, 日志缓存至重连
+	server.close()
+	time.Sleep(20 * time.Millisecond)
+	w.LogWrite(testRecord("lost"))
+	time.Sleep(20 * time.Millisecond)
+	for i := 3; i <= 5; i++ {
+		w.LogWrite(testRecord(fmt.Sprint(i)))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	server = startSocketServer(t, address)
+	server.expect(t, "3", "4", "5")
+	w.LogWrite(testRecord("6"))
+	server.expect(t, "6")
+}
+
+// 内存缓存最多bufferSize条, 超出的计入GetDropped
+func TestSocketBufferSize(t *testing.T) {
+	address := unusedAddress(t)
+	w := NewSocketLogWriter("sock", TRACE, "tcp", address).SetFormat("%M").SetBufferSize(2)
+	defer w.Close()
+
+	for i := 1; i <= 4; i++ {
+		w.LogWrite(testRecord(fmt.Sprint(i)))
+	}
+	for deadline := time.Now().Add(time.Second); w.GetDropped() < 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if dropped := w.GetDropped(); dropped < 2 {
+		t.Fatalf("GetDropped() = %d", dropped)
+	}
+
+	server := startSocketServer(t, address)
+	server.expect(t, "1", "2")
+	w.LogWrite(testRecord("5"))
+	server.expect(t, "5")
+}
+
+// 断线时缓存到文件, 关闭后残留的日志在下次创建时立即重发, 不必等到写日志
+func TestSocketFileSpill(t *testing.T) {
+	address := unusedAddress(t)
+	spillFile := filepath.Join(tempDir(t), "spill")
+
+	w := NewSocketLogWriter("sock", TRACE, "tcp", address).SetFormat("%M").SetSpillFile(spillFile, 0)
+	for i := 1; i <= 3; i++ {
+		w.LogWrite(testRecord(fmt.Sprint(i)))
+	}
+	w.Close()
+	if info, err := os.Stat(spillFile); err != nil || info.Size() == 0 {
+		t.Fatalf("spill file not written: %v %v", info, err)
+	}
+
+	server := startSocketServer(t, address)
+	w = NewSocketLogWriter("sock", TRACE, "tcp", address).SetFormat("%M").SetSpillFile(spillFile, 0)
+	server.expect(t, "1", "2", "3")
+	w.LogWrite(testRecord("4"))
+	server.expect(t, "4")
+	w.Close()
+
+	if info, err := os.Stat(spillFile); err != nil || info.Size() != 0 {
+		t.Errorf("spill file not truncated after replay: %v %v", info, err)
+	}
+}
+
+// 重发进度保存在.offset中, 重启后跳过已重发的日志, 并丢弃写了一半的日志
+func TestFileSpillOffset(t *testing.T) {
+	filename := filepath.Join(tempDir(t), "spill")
+	s, err := newFileSpill(filename, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"a\n", "bb\n", "ccc\n"} {
+		s.push([]byte(msg))
+	}
+	if !s.push([]byte("d\n")) || s.push(make([]byte, 1024)) {
+		t.Error("expect push to fail only beyond maxSize")
+	}
+	s.close()
+
+	// 模拟重发了a后退出, 且d只写了一半
+	offset := make([]byte, 8)
+	binary.BigEndian.PutUint64(offset, 4+2)
+	writeFile(t, filename+".offset", string(offset))
+	if err := os.Truncate(filename, 4+2+4+3+4+4+4+1); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = newFileSpill(filename, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if s.len() != 2 {
+		t.Fatalf("len() = %d, want 2", s.len())
+	}
+
+	client, server := net.Pipe()
+	received := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(server)
+		received <- string(data)
+	}()
+	if n, err := s.replay(client); n != 2 || err != nil {
+		t.Errorf("replay() = %d, %v", n, err)
+	}
+	_ = client.Close()
+	if got := <-received; got != "bb\nccc\n" {
+		t.Errorf("replayed %q", got)
+	}
+	if s.len() != 0 || s.fileSize() != 0 {
+		t.Errorf("after replay: len %d, size %d", s.len(), s.fileSize())
+	}
+}
@@ -0,0 +1,197 @@
+package log4j
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 包装一个LogWriter, 抑制重复日志并按调用位置采样:
+//   - window内Source与Message相同的日志只输出第一条, window结束时补一条"repeated N times"
+//   - 每个Source每秒输出前first条, 之后每thereafter条输出一条(thereafter<=0时不再输出)
+//
+// 第一条日志总会输出
+type SuppressLogWriter struct {
+	writer LogWriter
+
+	window     time.Duration
+	first      int
+	thereafter int
+
+	lock    sync.Mutex
+	repeats map[string]*repeatState // Source+Message -> 重复状态
+	samples map[string]int          // Source -> 本秒内的日志数
+	second  int64                   // samples统计的秒
+	sampled int64                   // 被采样丢弃的日志数
+
+	stop chan bool
+	done chan bool
+}
+
+type repeatState struct {
+	rec   *LogRecord // window内的第一条
+	start time.Time
+	count int // 被抑制的条数
+}
+
+// window<=0时不抑制重复日志; first<=0且thereafter<=0时不采样
+func NewSuppressLogWriter(writer LogWriter, window time.Duration, first, thereafter int) *SuppressLogWriter {
+	if (first > 0 || thereafter > 0) && first < 1 {
+		first = 1
+	}
+	w := &SuppressLogWriter{
+		writer:     writer,
+		window:     window,
+		first:      first,
+		thereafter: thereafter,
+		repeats:    map[string]*repeatState{},
+		samples:    map[string]int{},
+		stop:       make(chan bool),
+		done:       make(chan bool),
+	}
+
+	if window > 0 {
+		go w.flushLoop()
+	} else {
+		close(w.done)
+	}
+	return w
+}
+
+func (w *SuppressLogWriter) LogWrite(rec *LogRecord) {
+	if rec == nil {
+		w.writer.LogWrite(rec)
+		return
+	}
+
+	now := time.Now()
+	summary, write := (*LogRecord)(nil), true
+
+	w.lock.Lock()
+	if w.window > 0 {
+		summary, write = w.suppress(rec, now)
+	}
+	if write && w.first > 0 {
+		write = w.sample(rec, now)
+	}
+	w.lock.Unlock()
+
+	if summary != nil {
+		w.writer.LogWrite(summary)
+	}
+	if write {
+		w.writer.LogWrite(rec)
+	}
+}
+
+// 持有锁时调用; 返回上一个window的汇总日志(没有时为nil), 及rec是否输出
+func (w *SuppressLogWriter) suppress(rec *LogRecord, now time.Time) (*LogRecord, bool) {
+	key := rec.Source + "\x00" + rec.Message
+
+	state, ok := w.repeats[key]
+	if ok && now.Sub(state.start) < w.window {
+		state.count++
+		return nil, false
+	}
+
+	summary := (*LogRecord)(nil)
+	if ok {
+		summary = w.repeatedRecord(state, now)
+	}
+	w.repeats[key] = &repeatState{rec: rec, start: now}
+	return summary, true
+}
+
+// 持有锁时调用
+func (w *SuppressLogWriter) sample(rec *LogRecord, now time.Time) bool {
+	if second := now.Unix(); second != w.second {
+		w.second = second
+		w.samples = map[string]int{}
+	}
+
+	w.samples[rec.Source]++
+	n := w.samples[rec.Source]
+	if n <= w.first || (w.thereafter > 0 && (n-w.first)%w.thereafter == 0) {
+		return true
+	}
+	w.sampled++
+	return false
+}
+
+func (w *SuppressLogWriter) repeatedRecord(state *repeatState, now time.Time) *LogRecord {
+	if state.count == 0 {
+		return nil
+	}
+	rec := *state.rec
+	rec.Created = now
+	rec.Stack = ""
+	rec.Message = fmt.Sprintf("repeated %d times in %s: %s", state.count, now.Sub(state.start).Round(time.Millisecond), state.rec.Message)
+	return &rec
+}
+
+// 定时输出已结束的window的汇总日志, 并清理过期的状态
+func (w *SuppressLogWriter) flushLoop() {
+	ticker := time.NewTicker(w.window)
+	defer func() {
+		ticker.Stop()
+		close(w.done)
+	}()
+
+	for {
+		select {
+		case <-w.stop:
+			w.flush(true)
+			return
+		case <-ticker.C:
+			w.flush(false)
+		}
+	}
+}
+
+func (w *SuppressLogWriter) flush(all bool) {
+	now := time.Now()
+	summaries := make([]*LogRecord, 0)
+
+	w.lock.Lock()
+	for key, state := range w.repeats {
+		if all || now.Sub(state.start) >= w.window {
+			if summary := w.repeatedRecord(state, now); summary != nil {
+				summaries = append(summaries, summary)
+			}
+			delete(w.repeats, key)
+		}
+	}
+	w.lock.Unlock()
+
+	for _, summary := range summaries {
+		w.writer.LogWrite(summary)
+	}
+}
+
+// 输出尚未结束的window的汇总日志后关闭被包装的LogWriter
+func (w *SuppressLogWriter) Close() {
+	if w.window > 0 {
+		close(w.stop)
+	}
+	<-w.done
+	w.writer.Close()
+}
+
+func (w *SuppressLogWriter) IsPrivate() bool {
+	return w.writer.IsPrivate()
+}
+
+func (w *SuppressLogWriter) GetLevel() Level {
+	return w.writer.GetLevel()
+}
+
+func (w *SuppressLogWriter) Unwrap() LogWriter {
+	return w.writer
+}
+
+// 被采样丢弃的日志数
+func (w *SuppressLogWriter) GetSampled() int64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.sampled
+}
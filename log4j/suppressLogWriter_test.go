@@ -0,0 +1,125 @@
+package log4j
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 以指定的时间调用suppress, 不依赖真实时钟
+func TestSuppressRepeats(t *testing.T) {
+	w := NewSuppressLogWriter(&testLogWriter{}, time.Minute, 0, 0)
+	defer w.Close()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec := &LogRecord{Source: "main.run:12", Message: "connect fail", Stack: "stack"}
+	cases := []struct {
+		rec     *LogRecord
+		offset  time.Duration
+		write   bool
+		summary string
+	}{
+		{rec, 0, true, ""}, // 第一条总会输出
+		{rec, time.Second, false, ""},
+		{rec, 30 * time.Second, false, ""},
+		{&LogRecord{Source: "main.run:12", Message: "other"}, 40 * time.Second, true, ""},        // Message不同
+		{&LogRecord{Source: "main.run:13", Message: "connect fail"}, 40 * time.Second, true, ""}, // Source不同
+		{rec, 59 * time.Second, false, ""},
+		{rec, 61 * time.Second, true, "repeated 3 times in 1m1s: connect fail"}, // 新window的第一条输出, 并补上个window的汇总
+		{rec, 62 * time.Second, false, ""},
+	}
+	for i, c := range cases {
+		w.lock.Lock()
+		summary, write := w.suppress(c.rec, start.Add(c.offset))
+		w.lock.Unlock()
+
+		if write != c.write {
+			t.Errorf("#%d: write = %v, want %v", i, write, c.write)
+		}
+		if c.summary == "" {
+			if summary != nil {
+				t.Errorf("#%d: unexpected summary %q", i, summary.Message)
+			}
+			continue
+		}
+		if summary == nil || summary.Message != c.summary || summary.Source != rec.Source || summary.Stack != "" {
+			t.Errorf("#%d: summary %+v, want %q", i, summary, c.summary)
+		}
+	}
+}
+
+func TestSuppressSampling(t *testing.T) {
+	second := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		first, thereafter int
+		want              string // 同一秒内同一Source连续9条的输出情况
+		sampled           int64
+	}{
+		{2, 3, "++--+--+-", 5},
+		{2, 0, "++-------", 7},
+		{0, 3, "+--+--+--", 6}, // first至少为1, 第一条总会输出
+		{1, 1, "+++++++++", 0},
+	}
+	for _, c := range cases {
+		w := NewSuppressLogWriter(&testLogWriter{}, 0, c.first, c.thereafter)
+		got := ""
+		for i := 0; i < 9; i++ {
+			if w.sample(&LogRecord{Source: "a.go:1"}, second.Add(time.Duration(i)*time.Millisecond)) {
+				got += "+"
+			} else {
+				got += "-"
+			}
+		}
+		if got != c.want || w.GetSampled() != c.sampled {
+			t.Errorf("first %d, thereafter %d: got %s (sampled %d), want %s (sampled %d)",
+				c.first, c.thereafter, got, w.GetSampled(), c.want, c.sampled)
+		}
+
+		// 其他Source单独计数, 下一秒重新计数
+		if !w.sample(&LogRecord{Source: "b.go:1"}, second) {
+			t.Errorf("first %d: other source sampled", c.first)
+		}
+		if !w.sample(&LogRecord{Source: "a.go:1"}, second.Add(time.Second)) {
+			t.Errorf("first %d: next second sampled", c.first)
+		}
+		w.Close()
+	}
+}
+
+// Close时输出未结束window的汇总, 并关闭被包装的LogWriter
+func TestSuppressFlushOnClose(t *testing.T) {
+	out := &testLogWriter{}
+	w := NewSuppressLogWriter(out, time.Hour, 0, 0)
+	for i := 0; i < 4; i++ {
+		w.LogWrite(&LogRecord{Level: ERROR, Created: time.Now(), Source: "main.run:12", Message: "disk full"})
+	}
+	w.LogWrite(nil) // 空行直接输出
+	if got := out.messages(); strings.Join(got, "|") != "disk full" {
+		t.Fatalf("before Close: %v", got)
+	}
+
+	w.Close()
+	got := out.messages()
+	if len(got) != 2 || !strings.HasPrefix(got[1], "repeated 3 times in ") || !strings.HasSuffix(got[1], ": disk full") {
+		t.Errorf("after Close: %v", got)
+	}
+	if recs := out.records(); len(recs) != 3 || recs[1] != nil || recs[2].Level != ERROR {
+		t.Errorf("records: %v", recs)
+	}
+}
+
+// 定时输出已结束window的汇总, 不必等到下一条重复日志
+func TestSuppressFlushLoop(t *testing.T) {
+	out := &testLogWriter{}
+	w := NewSuppressLogWriter(out, 20*time.Millisecond, 0, 0)
+	defer w.Close()
+
+	w.LogWrite(&LogRecord{Source: "main.run:12", Message: "retry"})
+	w.LogWrite(&LogRecord{Source: "main.run:12", Message: "retry"})
+	for deadline := time.Now().Add(time.Second); len(out.messages()) < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := out.messages(); len(got) != 2 || !strings.HasPrefix(got[1], "repeated 1 times") {
+		t.Errorf("got %v", got)
+	}
+}
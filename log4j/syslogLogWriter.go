@@ -0,0 +1,341 @@
+package log4j
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslog报文格式
+const (
+	SyslogRFC5424 = "5424"
+	SyslogRFC3164 = "3164"
+)
+
+const (
+	syslogRedialInterval = time.Second // 连接失败后, 两次重连的最小间隔
+	syslogDialTimeout    = time.Second * 5
+	syslogWriteTimeout   = time.Second * 5 // syslog不读取数据时, 写超时后重连, 避免阻塞写日志
+)
+
+// network为空时依次尝试的本机syslog socket
+var syslogLocalAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3, "warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// 日志级别默认对应的syslog severity
+var defaultSyslogSeverity = map[Level]int{
+	TRACE:   7,
+	DEBUG:   7,
+	INFO:    6,
+	WARNING: 4,
+	ERROR:   3,
+	FATAL:   2,
+}
+
+// 通过udp、tcp、unix socket发送日志到syslog, 连接断开时自动重连
+type SyslogLogWriter struct {
+	*logChannel
+
+	level   Level
+	tag     string
+	closeCh chan bool
+	logFormat
+	private bool
+
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+	rfc      string
+	severity map[Level]int
+
+	conn     net.Conn
+	stream   bool      // tcp、unix为流式连接, 需要分帧
+	lastDial time.Time // 最近一次连接的时间, 用于限制重连频率
+	failed   bool      // 已输出连接失败的提示
+	lost     int64     // 连接失败期间丢弃的日志数
+}
+
+// network为空时连接本机syslog(/dev/log等); 连接失败不影响创建, 写日志时会重连
+func NewSyslogLogWriter(tag string, level Level, network, address string) *SyslogLogWriter {
+	hostname, _ := os.Hostname()
+	writer := &SyslogLogWriter{
+		level:      level,
+		tag:        tag,
+		logChannel: newLogChannel(LogBufferLength),
+		closeCh:    make(chan bool),
+		logFormat:  logFormat{format: "%M"},
+		network:    network,
+		address:    address,
+		facility:   syslogFacilities["user"],
+		appName:    filepath.Base(os.Args[0]),
+		hostname:   hostname,
+		rfc:        SyslogRFC5424,
+		severity:   defaultSyslogSeverity,
+	}
+	go writer.run()
+
+	printlnIO(os.Stdout, "INFO", "syslogLogWriter[%s], create success, network:%s, address:%s", tag, network, address)
+	return writer
+}
+
+func (w *SyslogLogWriter) run() {
+	defer func() {
+		w.closeConn()
+		w.closeCh <- true
+	}()
+
+	for rec := range w.logChannel.ch {
+		w.send(rec)
+
+		if notice := w.logChannel.droppedNotice(); notice != nil {
+			w.send(notice)
+		}
+	}
+}
+
+func (w *SyslogLogWriter) send(rec *LogRecord) {
+	if rec == nil {
+		return // 空行对syslog无意义
+	}
+
+	if w.conn == nil && !w.dial() {
+		w.lost++
+		return
+	}
+
+	msg := w.frame(rec)
+	if err := w.write(msg); err == nil {
+		return
+	}
+
+	// 写失败时重连并重发一次
+	w.closeConn()
+	if !w.dial() {
+		w.lost++
+		return
+	}
+	if err := w.write(msg); err != nil {
+		w.closeConn()
+		w.lost++
+		w.reportFail(err)
+	}
+}
+
+func (w *SyslogLogWriter) write(msg []byte) error {
+	_ = w.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+func (w *SyslogLogWriter) dial() bool {
+	if time.Since(w.lastDial) < syslogRedialInterval {
+		return false
+	}
+	w.lastDial = time.Now()
+
+	conn, err := net.Conn(nil), error(nil)
+	if w.network == "" {
+		conn, err = dialLocalSyslog()
+	} else {
+		conn, err = net.DialTimeout(w.network, w.address, syslogDialTimeout)
+	}
+	if err != nil {
+		w.reportFail(err)
+		return false
+	}
+
+	w.conn = conn
+	switch conn.LocalAddr().Network() {
+	case "tcp", "tcp4", "tcp6", "unix":
+		w.stream = true
+	default:
+		w.stream = false
+	}
+
+	if w.failed {
+		printlnIO(os.Stdout, "INFO", "syslogLogWriter[%s] reconnected, lost %d records", w.tag, w.lost)
+		w.failed, w.lost = false, 0
+	}
+	return true
+}
+
+func dialLocalSyslog() (net.Conn, error) {
+	err := error(nil)
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, address := range syslogLocalAddresses {
+			conn, e := net.DialTimeout(network, address, syslogDialTimeout)
+			if e == nil {
+				return conn, nil
+			}
+			err = e
+		}
+	}
+	return nil, err
+}
+
+// 连接失败只提示一次, 直到重连成功
+func (w *SyslogLogWriter) reportFail(err error) {
+	if !w.failed {
+		w.failed = true
+		printlnIO(os.Stderr, "ERROR", "syslogLogWriter[%s] connect %s:%s fail, err:%s", w.tag, w.network, w.address, err.Error())
+	}
+}
+
+func (w *SyslogLogWriter) closeConn() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// 按rfc生成报文; 流式连接下5424使用octet counting, 3164以换行分隔
+func (w *SyslogLogWriter) frame(rec *LogRecord) []byte {
+	msg := &bytes.Buffer{}
+	_, _ = fPrintFormatLog(msg, w.format, rec)
+	body := strings.TrimRight(msg.String(), "\n")
+
+	severity, ok := w.severity[rec.Level]
+	if !ok {
+		severity = defaultSyslogSeverity[rec.Level]
+	}
+	pri := w.facility*8 + severity
+
+	out := &bytes.Buffer{}
+	if w.rfc == SyslogRFC3164 {
+		fmt.Fprintf(out, "<%d>%s %s %s[%d]: ", pri, rec.Created.Format(time.Stamp), syslogField(w.hostname), syslogField(w.appName), os.Getpid())
+		if w.stream {
+			body = strings.Replace(body, "\n", "#012", -1)
+		}
+		out.WriteString(body)
+		if w.stream {
+			out.WriteByte('\n')
+		}
+		return out.Bytes()
+	}
+
+	msgId := syslogField(rec.Tag)
+	fmt.Fprintf(out, "<%d>1 %s %s %s %d %s - ", pri, rec.Created.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogField(w.hostname), syslogField(w.appName), os.Getpid(), msgId)
+	out.WriteString(body)
+
+	if w.stream {
+		return append([]byte(strconv.Itoa(out.Len())+" "), out.Bytes()...)
+	}
+	return out.Bytes()
+}
+
+// header中的字段不能为空或含空格
+func syslogField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Replace(s, " ", "_", -1)
+}
+
+func parseSyslogFacility(str string) (int, bool) {
+	if facility, ok := syslogFacilities[strings.ToLower(str)]; ok {
+		return facility, true
+	}
+	if facility, err := strconv.Atoi(str); err == nil && facility >= 0 && facility <= 23 {
+		return facility, true
+	}
+	return 0, false
+}
+
+// 格式如"DEBUG:debug,ERROR:3", severity可以是名称或0-7
+func parseSyslogSeverity(str string) (map[Level]int, bool) {
+	severity := map[Level]int{}
+	for _, item := range strings.Split(str, ",") {
+		pair := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(pair) != 2 {
+			return nil, false
+		}
+		lvl, ok := parseLevel(strings.TrimSpace(pair[0]))
+		if !ok {
+			return nil, false
+		}
+		value := strings.TrimSpace(pair[1])
+		if s, ok := syslogSeverities[strings.ToLower(value)]; ok {
+			severity[lvl] = s
+		} else if s, err := strconv.Atoi(value); err == nil && s >= 0 && s <= 7 {
+			severity[lvl] = s
+		} else {
+			return nil, false
+		}
+	}
+	return severity, true
+}
+
+func (w *SyslogLogWriter) LogWrite(rec *LogRecord) {
+	w.logChannel.put(rec)
+}
+
+func (w *SyslogLogWriter) Close() {
+	w.logChannel.close()
+	<-w.closeCh // 等待run()将日志全部发送后return
+	printlnIO(os.Stdout, "INFO", "syslogLogWriter[%s] is closed", w.tag)
+}
+
+func (w *SyslogLogWriter) IsPrivate() bool {
+	return w.private
+}
+
+func (w *SyslogLogWriter) GetLevel() Level {
+	return w.level
+}
+
+// 默认为"%M", 时间、级别等由syslog header携带
+func (w *SyslogLogWriter) SetFormat(format string) *SyslogLogWriter {
+	w.setFormat(format)
+	return w
+}
+
+func (w *SyslogLogWriter) SetPrivate(private bool) *SyslogLogWriter {
+	w.private = private
+	return w
+}
+
+func (w *SyslogLogWriter) SetFacility(facility int) *SyslogLogWriter {
+	w.facility = facility
+	return w
+}
+
+func (w *SyslogLogWriter) SetAppName(appName string) *SyslogLogWriter {
+	if appName != "" {
+		w.appName = appName
+	}
+	return w
+}
+
+// SyslogRFC5424(默认) 或 SyslogRFC3164
+func (w *SyslogLogWriter) SetRFC(rfc string) *SyslogLogWriter {
+	if rfc != "" {
+		w.rfc = rfc
+	}
+	return w
+}
+
+// 未设置的级别使用默认severity
+func (w *SyslogLogWriter) SetSeverity(severity map[Level]int) *SyslogLogWriter {
+	if len(severity) > 0 {
+		w.severity = severity
+	}
+	return w
+}
@@ -0,0 +1,161 @@
+package log4j
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSyslogWriter(rfc string, stream bool) *SyslogLogWriter {
+	return &SyslogLogWriter{
+		logFormat: logFormat{format: "%M"},
+		facility:  syslogFacilities["local0"],
+		appName:   "my app",
+		hostname:  "host",
+		rfc:       rfc,
+		severity:  defaultSyslogSeverity,
+		stream:    stream,
+	}
+}
+
+func TestSyslogFrameRFC5424(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.Local)
+	rec := &LogRecord{Level: ERROR, Created: created, Message: "disk full\nretrying", Tag: "order"}
+
+	// local0(16)*8 + err(3)
+	want := fmt.Sprintf("<131>1 %s host my_app %d order - disk full\nretrying",
+		created.Format("2006-01-02T15:04:05.000000Z07:00"), os.Getpid())
+
+	if got := string(newTestSyslogWriter(SyslogRFC5424, false).frame(rec)); got != want {
+		t.Errorf("datagram frame:\n got %q\nwant %q", got, want)
+	}
+
+	// 流式连接使用octet counting: "<长度> <报文>"
+	if got := string(newTestSyslogWriter(SyslogRFC5424, true).frame(rec)); got != strconv.Itoa(len(want))+" "+want {
+		t.Errorf("stream frame:\n got %q\nwant %q", got, strconv.Itoa(len(want))+" "+want)
+	}
+}
+
+func TestSyslogFrameRFC3164(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	rec := &LogRecord{Level: WARNING, Created: created, Message: "slow\nquery"}
+
+	// local0(16)*8 + warning(4)
+	header := fmt.Sprintf("<132>%s host my_app[%d]: ", created.Format(time.Stamp), os.Getpid())
+
+	if got, want := string(newTestSyslogWriter(SyslogRFC3164, false).frame(rec)), header+"slow\nquery"; got != want {
+		t.Errorf("datagram frame:\n got %q\nwant %q", got, want)
+	}
+	// 流式连接以换行分隔, 消息中的换行转义为#012
+	if got, want := string(newTestSyslogWriter(SyslogRFC3164, true).frame(rec)), header+"slow#012query\n"; got != want {
+		t.Errorf("stream frame:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSyslogSeverityMap(t *testing.T) {
+	w := newTestSyslogWriter(SyslogRFC5424, false)
+	severity, ok := parseSyslogSeverity("DEBUG:notice, ERROR:2")
+	if !ok {
+		t.Fatal("parseSyslogSeverity fail")
+	}
+	w.severity = severity
+
+	for lvl, want := range map[Level]string{DEBUG: "<133>", ERROR: "<130>", INFO: "<134>"} {
+		if got := string(w.frame(&LogRecord{Level: lvl, Created: time.Now()})); !strings.HasPrefix(got, want) {
+			t.Errorf("%s: got %q, want prefix %s", lvl, got, want)
+		}
+	}
+	if _, ok := parseSyslogSeverity("DEBUG:loud"); ok {
+		t.Error("expect invalid severity")
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := NewSyslogLogWriter("syslog", INFO, "udp", conn.LocalAddr().String())
+	defer w.Close()
+	w.LogWrite(&LogRecord{Level: INFO, Created: time.Now(), Message: "hello udp"})
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// user(1)*8 + info(6)
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<14>1 ") || !strings.HasSuffix(msg, " - hello udp") {
+		t.Errorf("got %q", msg)
+	}
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	// 先占用一个端口再释放, 使第一次连接失败
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	w := NewSyslogLogWriter("syslog", INFO, "tcp", address)
+	defer w.Close()
+	w.LogWrite(&LogRecord{Level: INFO, Created: time.Now(), Message: "lost"})
+	time.Sleep(200 * time.Millisecond) // 等待连接失败
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skipf("listen %s again fail: %s", address, err)
+	}
+	defer listener.Close()
+
+	// 重连间隔为syslogRedialInterval
+	time.Sleep(syslogRedialInterval)
+	w.LogWrite(&LogRecord{Level: INFO, Created: time.Now(), Message: "first"})
+	w.LogWrite(&LogRecord{Level: INFO, Created: time.Now(), Message: "second"})
+
+	_ = listener.(*net.TCPListener).SetDeadline(time.Now().Add(3 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"first", "second"} {
+		msg, err := readOctetCounted(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(msg, " - "+want) {
+			t.Errorf("got %q, want message %s", msg, want)
+		}
+	}
+}
+
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
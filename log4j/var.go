@@ -31,18 +31,23 @@ var (
 type Level int
 
 const (
-	DEBUG Level = iota
+	TRACE Level = iota
+	DEBUG
 	INFO
 	WARNING
 	ERROR
+	FATAL // 输出后关闭所有LogWriter并退出进程
 )
 
 var (
-	levelStrings = [...]string{"DEBG", "INFO", "WARN", "EROR"}
+	levelStrings = [...]string{"TRAC", "DEBG", "INFO", "WARN", "EROR", "FATL"}
+
+	// 配置文件及json格式输出使用的级别名称
+	levelNames = [...]string{"TRACE", "DEBUG", "INFO", "WARNING", "ERROR", "FATAL"}
 )
 
 func (l Level) String() string {
-	if l < 0 || int(l) > len(levelStrings) {
+	if l < 0 || int(l) >= len(levelStrings) {
 		return "UNKNOWN"
 	}
 	return levelStrings[int(l)]
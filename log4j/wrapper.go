@@ -3,8 +3,11 @@
 package log4j
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"io"
+	"net/http"
+	"time"
 )
 
 const (
@@ -13,33 +16,89 @@ const (
 )
 
 var (
-	globalHandler *loggerHandler
+	defaultLogger *Logger
 )
 
 func init() {
-	globalHandler = newDefaultLogger(DEBUG)
+	defaultLogger = newDefaultLogger(DEBUG)
 }
 
 func LoadConfiguration(filename string) {
-	globalHandler.loadConfiguration(filename)
+	defaultLogger.LoadConfiguration(filename)
+}
+
+// 配置有误时返回error, 不退出进程, 默认的console输出保持不变
+func LoadConfigurationE(filename string) error {
+	return defaultLogger.LoadConfigurationE(filename)
+}
+
+func LoadConfigurationFromReader(reader io.Reader) error {
+	return defaultLogger.LoadConfigurationFromReader(reader)
+}
+
+// 重新加载配置文件, 失败时保留原配置
+func Reload() error {
+	return defaultLogger.Reload()
+}
+
+// 定时检查配置文件是否修改, 修改后自动Reload
+func WatchConfiguration(interval time.Duration) error {
+	return defaultLogger.WatchConfiguration(interval)
+}
+
+func StopWatch() {
+	defaultLogger.StopWatch()
 }
 
 func Close() {
-	globalHandler.close()
+	defaultLogger.Close()
 }
 
 func CloseByTag(logTag string) {
-	globalHandler.closeByTag(logTag)
+	defaultLogger.CloseByTag(logTag)
 }
 
 func AddFileLoggerIfNotExist(tag string, lv Level, logProperty *LogProperty) bool {
-	return globalHandler.addFileLoggerIfNotExist(tag, lv, logProperty)
+	return defaultLogger.AddFileLoggerIfNotExist(tag, lv, logProperty)
 }
 
 func GetLogFilePath() string {
-	return globalHandler.getLogFilePath()
+	return defaultLogger.GetLogFilePath()
+}
+
+// 运行时修改tag对应LogWriter的级别, 重新加载配置后仍保留(新配置中没有该tag时丢弃)
+func SetLevel(tag string, lvl Level) error {
+	return defaultLogger.SetLevel(tag, lvl)
+}
+
+// 如 SetLevelFor("stdout", DEBUG, 10*time.Minute), 到期后恢复配置中的级别
+func SetLevelFor(tag string, lvl Level, duration time.Duration) error {
+	return defaultLogger.SetLevelFor(tag, lvl, duration)
+}
+
+func ResetLevel(tag string) {
+	defaultLogger.ResetLevel(tag)
+}
+
+func GetLevels() map[string]Level {
+	return defaultLogger.GetLevels()
+}
+
+// 查看及修改LogWriter级别, 如 http.Handle("/debug/log-levels", log4j.LevelHandler())
+func LevelHandler() http.Handler {
+	return defaultLogger.LevelHandler()
+}
+
+func MemoryLogWriters() map[string]*MemoryLogWriter {
+	return defaultLogger.MemoryLogWriters()
+}
+
+// 查询type为memory的LogWriter中的日志, 如 http.Handle("/debug/logs", log4j.MemoryHandler())
+func MemoryHandler() http.Handler {
+	return defaultLogger.MemoryHandler()
 }
 
+// 同时实现了 Context() context.Context 时, 日志携带其中的traceId等上下文值
 type LogBuffer interface {
 	String() string
 	PrintStack() bool
@@ -48,225 +107,110 @@ type LogBuffer interface {
 }
 
 func Log(logBuffer LogBuffer) {
-	logTxt, skip := logBuffer.String(), logBuffer.RuntimeSkip(RUNTIME_SKIP)
-	switch lv := logBuffer.GetLogLevel(); lv {
-	case DEBUG, INFO, WARNING:
-		globalHandler.addLogString(skip, lv, false, "", logTxt)
-	default:
-		globalHandler.addLogString(skip, lv, logBuffer.PrintStack(), "", logTxt)
-	}
+	defaultLogger.addLogBuffer("", false, logBuffer)
 }
 
 func LogIfError(logBuffer LogBuffer) {
-	if logBuffer.GetLogLevel() == ERROR {
-		logTxt, skip := logBuffer.String(), logBuffer.RuntimeSkip(RUNTIME_SKIP)
-		globalHandler.addLogString(skip, ERROR, logBuffer.PrintStack(), "", logTxt)
-	}
+	defaultLogger.addLogBuffer("", true, logBuffer)
 }
 
 func LogTag(tag string, logBuffer LogBuffer) {
-	logTxt, skip := logBuffer.String(), logBuffer.RuntimeSkip(RUNTIME_SKIP)
-	switch lv := logBuffer.GetLogLevel(); lv {
-	case DEBUG, INFO, WARNING:
-		globalHandler.addLogString(skip, lv, false, tag, logTxt)
-	default:
-		globalHandler.addLogString(skip, lv, logBuffer.PrintStack(), tag, logTxt)
-	}
+	defaultLogger.addLogBuffer(tag, false, logBuffer)
 }
 
 func LogTagIfError(tag string, logBuffer LogBuffer) {
-	if logBuffer.GetLogLevel() == ERROR {
-		logTxt, skip := logBuffer.String(), logBuffer.RuntimeSkip(RUNTIME_SKIP)
-		globalHandler.addLogString(skip, ERROR, logBuffer.PrintStack(), tag, logTxt)
-	}
+	defaultLogger.addLogBuffer(tag, true, logBuffer)
+}
+
+func Trace(arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(TRACE, false, "", nil, arg0, args...)
+}
+
+func TraceTag(tag string, arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(TRACE, false, tag, nil, arg0, args...)
 }
 
 func Debug(arg0 interface{}, args ...interface{}) {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, DEBUG, false, "", first, args...)
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(DEBUG, false, "", logString, src)
-	default:
-		if args != nil {
-			globalHandler.addLogString(RUNTIME_SKIP, DEBUG, false, "", "%+v", append([]interface{}{arg0}, args))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, DEBUG, false, "", "%+v", arg0)
-		}
-	}
+	defaultLogger.addLogArgs(DEBUG, false, "", nil, arg0, args...)
 }
 
 func DebugTag(tag string, arg0 interface{}, args ...interface{}) {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, DEBUG, false, tag, first, args...)
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(DEBUG, false, tag, logString, src)
-	default:
-		if args != nil {
-			globalHandler.addLogString(RUNTIME_SKIP, DEBUG, false, tag, "%+v", append([]interface{}{arg0}, args))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, DEBUG, false, tag, "%+v", arg0)
-		}
-	}
+	defaultLogger.addLogArgs(DEBUG, false, tag, nil, arg0, args...)
 }
 
 func Info(arg0 interface{}, args ...interface{}) {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, INFO, false, "", first, args...)
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(INFO, false, "", logString, src)
-	default:
-		if args != nil {
-			globalHandler.addLogString(RUNTIME_SKIP, INFO, false, "", "%+v", append([]interface{}{arg0}, args))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, INFO, false, "", "%+v", arg0)
-		}
-	}
+	defaultLogger.addLogArgs(INFO, false, "", nil, arg0, args...)
 }
 
 func InfoTag(tag string, arg0 interface{}, args ...interface{}) {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, INFO, false, tag, first, args...)
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(INFO, false, tag, logString, src)
-	default:
-		if args != nil {
-			globalHandler.addLogString(RUNTIME_SKIP, INFO, false, tag, "%+v", append([]interface{}{arg0}, args))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, INFO, false, tag, "%+v", arg0)
-		}
-	}
+	defaultLogger.addLogArgs(INFO, false, tag, nil, arg0, args...)
 }
 
 func Warn(arg0 interface{}, args ...interface{}) error {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, WARNING, false, "", first, args...)
-		return errors.New(fmt.Sprintf(first, args...))
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(WARNING, false, "", logString, src)
-		return errors.New(logString)
-	default:
-		if args != nil {
-			slice := append([]interface{}{arg0}, args)
-			globalHandler.addLogString(RUNTIME_SKIP, WARNING, false, "", "%+v", slice)
-			return errors.New(fmt.Sprintf("%+v", slice))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, WARNING, false, "", "%+v", arg0)
-			return errors.New(fmt.Sprintf("%+v", arg0))
-		}
-	}
+	return errors.New(defaultLogger.addLogArgs(WARNING, false, "", nil, arg0, args...))
 }
 
 func WarnTag(tag string, arg0 interface{}, args ...interface{}) error {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, WARNING, false, tag, first, args...)
-		return errors.New(fmt.Sprintf(first, args...))
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(WARNING, false, tag, logString, src)
-		return errors.New(logString)
-	default:
-		if args != nil {
-			slice := append([]interface{}{arg0}, args)
-			globalHandler.addLogString(RUNTIME_SKIP, WARNING, false, tag, "%+v", slice)
-			return errors.New(fmt.Sprintf("%+v", slice))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, WARNING, false, tag, "%+v", arg0)
-			return errors.New(fmt.Sprintf("%+v", arg0))
-		}
-	}
+	return errors.New(defaultLogger.addLogArgs(WARNING, false, tag, nil, arg0, args...))
 }
 
 func Error(arg0 interface{}, args ...interface{}) error {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, ERROR, false, "", first, args...)
-		return errors.New(fmt.Sprintf(first, args...))
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(ERROR, false, "", logString, src)
-		return errors.New(logString)
-	default:
-		if args != nil {
-			slice := append([]interface{}{arg0}, args)
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, false, "", "%+v", slice)
-			return errors.New(fmt.Sprintf("%+v", slice))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, false, "", "%+v", arg0)
-			return errors.New(fmt.Sprintf("%+v", arg0))
-		}
-	}
+	return errors.New(defaultLogger.addLogArgs(ERROR, false, "", nil, arg0, args...))
 }
 
 func ErrorTag(tag string, arg0 interface{}, args ...interface{}) {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, ERROR, false, tag, first, args...)
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(ERROR, false, tag, logString, src)
-	default:
-		if args != nil {
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, false, tag, "%+v", append([]interface{}{arg0}, args))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, false, tag, "%+v", arg0)
-		}
-	}
+	defaultLogger.addLogArgs(ERROR, false, tag, nil, arg0, args...)
 }
 
 // error log with stack info
 func ErrorStack(arg0 interface{}, args ...interface{}) error {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, ERROR, true, "", first, args...)
-		return errors.New(fmt.Sprintf(first, args...))
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(ERROR, true, "", logString, src)
-		return errors.New(logString)
-	default:
-		if args != nil {
-			slice := append([]interface{}{arg0}, args)
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, true, "", "%+v", slice)
-			return errors.New(fmt.Sprintf("%+v", slice))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, true, "", "%+v", arg0)
-			return errors.New(fmt.Sprintf("%+v", arg0))
-		}
-	}
+	return errors.New(defaultLogger.addLogArgs(ERROR, true, "", nil, arg0, args...))
 }
 
 func ErrorTagStack(tag string, arg0 interface{}, args ...interface{}) error {
-	switch first := arg0.(type) {
-	case string:
-		globalHandler.addLogString(RUNTIME_SKIP, ERROR, true, tag, first, args...)
-		return errors.New(fmt.Sprintf(first, args...))
-	case func() (log string, src string):
-		logString, src := first()
-		globalHandler.addLogFunc(ERROR, true, tag, logString, src)
-		return errors.New(logString)
-	default:
-		if args != nil {
-			slice := append([]interface{}{arg0}, args)
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, true, tag, "%+v", slice)
-			return errors.New(fmt.Sprintf("%+v", slice))
-		} else {
-			globalHandler.addLogString(RUNTIME_SKIP, ERROR, true, tag, "%+v", arg0)
-			return errors.New(fmt.Sprintf("%+v", arg0))
-		}
-	}
+	return errors.New(defaultLogger.addLogArgs(ERROR, true, tag, nil, arg0, args...))
+}
+
+// fatal log with stack info, 之后关闭所有LogWriter(写完缓冲区中的日志)并退出进程
+func Fatal(arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(FATAL, true, "", nil, arg0, args...)
+	defaultLogger.exit()
+}
+
+func FatalTag(tag string, arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(FATAL, true, tag, nil, arg0, args...)
+	defaultLogger.exit()
+}
+
+func TraceCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(TRACE, false, "", WithContext(ctx), arg0, args...)
+}
+
+func DebugCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(DEBUG, false, "", WithContext(ctx), arg0, args...)
+}
+
+func InfoCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(INFO, false, "", WithContext(ctx), arg0, args...)
+}
+
+func WarnCtx(ctx context.Context, arg0 interface{}, args ...interface{}) error {
+	return errors.New(defaultLogger.addLogArgs(WARNING, false, "", WithContext(ctx), arg0, args...))
+}
+
+func ErrorCtx(ctx context.Context, arg0 interface{}, args ...interface{}) error {
+	return errors.New(defaultLogger.addLogArgs(ERROR, false, "", WithContext(ctx), arg0, args...))
+}
+
+func ErrorStackCtx(ctx context.Context, arg0 interface{}, args ...interface{}) error {
+	return errors.New(defaultLogger.addLogArgs(ERROR, true, "", WithContext(ctx), arg0, args...))
+}
+
+func FatalCtx(ctx context.Context, arg0 interface{}, args ...interface{}) {
+	defaultLogger.addLogArgs(FATAL, true, "", WithContext(ctx), arg0, args...)
+	defaultLogger.exit()
 }
 
 func EmptyLine(lvl Level, tag string) {
-	globalHandler.addEmptyLine(lvl, tag)
+	defaultLogger.EmptyLine(lvl, tag)
 }
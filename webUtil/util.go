@@ -8,11 +8,14 @@ package webUtil
 
 import (
     "bytes"
+    "crypto/rand"
+    "encoding/hex"
     "encoding/json"
     "fmt"
     "github.com/ZhouJunjun/goLib/commonUtil/logBuffer"
     "github.com/ZhouJunjun/goLib/commonUtil/s"
     "github.com/ZhouJunjun/goLib/commonUtil/t"
+    "github.com/ZhouJunjun/goLib/log4j"
     "github.com/ZhouJunjun/goLib/util"
     "net"
     "net/http"
@@ -403,10 +406,21 @@ func GetHostNameIp() (string, error) {
     return addrSlice[0], nil
 }
 
-// 标准格式request log；包含url、postBody、登录userId
+// 把请求头X-Request-Id(没有则随机生成)作为requestId存入request.Context(), 之后GetRequestLog及log4j.InfoCtx等日志都会携带
+func WithRequestId(request *http.Request) *http.Request {
+    requestId := request.Header.Get("X-Request-Id")
+    if requestId == "" {
+        bs := make([]byte, 8)
+        _, _ = rand.Read(bs)
+        requestId = hex.EncodeToString(bs)
+    }
+    return request.WithContext(log4j.ContextWith(request.Context(), "requestId", requestId))
+}
+
+// 标准格式request log；包含url、postBody、登录userId; 携带request.Context()中的traceId等日志上下文
 func GetRequestLog(request *http.Request) *logBuffer.Buffer {
 
-    buffer := new(logBuffer.Buffer).Append("url:").Append(request.URL.Path)
+    buffer := new(logBuffer.Buffer).SetContext(request.Context()).Append("url:").Append(request.URL.Path)
 
     if len(request.URL.RawQuery) > 0 {
         buffer.Append("?").Append(request.URL.RawQuery)